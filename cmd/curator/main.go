@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"bdemetris/curator/internal/app"
 	"bdemetris/curator/pkg/database"
+	"bdemetris/curator/pkg/model"
 	"bdemetris/curator/pkg/store"
 
 	"github.com/slack-go/slack"
@@ -26,31 +29,132 @@ func main() {
 	}
 
 	cfg := store.StoreConfig{
-		Provider:         provider,
-		DynamoDBEndpoint: os.Getenv("DYNAMODB_ENDPOINT"), // e.g., "http://localhost:8000"
-		JiraToken:        os.Getenv("JIRA_TOKEN"),
-		JiraBaseURL:      os.Getenv("JIRA_BASE_URL"),
-		JiraEmail:        os.Getenv("JIRA_EMAIL"),
+		Provider:                  provider,
+		DynamoDBEndpoint:          os.Getenv("DYNAMODB_ENDPOINT"), // e.g., "http://localhost:8000"
+		DynamoDBRegion:            os.Getenv("AWS_REGION"),
+		DynamoDBProfile:           os.Getenv("AWS_PROFILE"),
+		DynamoDBTable:             os.Getenv("DYNAMODB_TABLE"),
+		DynamoDBEventsTable:       os.Getenv("DYNAMODB_EVENTS_TABLE"),
+		DynamoDBReservationsTable: os.Getenv("DYNAMODB_RESERVATIONS_TABLE"),
+		DynamoDBWaitlistTable:     os.Getenv("DYNAMODB_WAITLIST_TABLE"),
+		DynamoDBBillingMode:       os.Getenv("DYNAMODB_BILLING_MODE"),
+		DynamoDBReadCapacity:      os.Getenv("DYNAMODB_READ_CAPACITY"),
+		DynamoDBWriteCapacity:     os.Getenv("DYNAMODB_WRITE_CAPACITY"),
+		JiraToken:                 os.Getenv("JIRA_TOKEN"),
+		JiraBaseURL:               os.Getenv("JIRA_BASE_URL"),
+		JiraEmail:                 os.Getenv("JIRA_EMAIL"),
+		JiraMappingFile:           os.Getenv("JIRA_MAPPING_FILE"),
+		JiraObjectTypeID:          os.Getenv("JIRA_OBJECT_TYPE_ID"),
+		JiraAttributeIDs:          os.Getenv("JIRA_ATTRIBUTE_IDS"),
+		MemorySeedFile:            os.Getenv("MEMORY_SEED_FILE"),
+		SQLitePath:                os.Getenv("SQLITE_PATH"),
+		CacheTTL:                  os.Getenv("STORE_CACHE_TTL"),
+		RetryMaxAttempts:          os.Getenv("STORE_RETRY_ATTEMPTS"),
+		CallTimeout:               os.Getenv("STORE_CALL_TIMEOUT"),
 	}
 
 	constructors := map[string]store.StoreConstructor{
 		store.ProviderDynamoDB: func(ctx context.Context, cfg store.StoreConfig) (store.Store, error) {
-			return database.NewDynamoStore(ctx, cfg.DynamoDBEndpoint)
+			billingMode, err := database.ParseDynamoBillingMode(cfg.DynamoDBBillingMode)
+			if err != nil {
+				return nil, err
+			}
+			readCapacity, err := parseCapacity("DYNAMODB_READ_CAPACITY", cfg.DynamoDBReadCapacity)
+			if err != nil {
+				return nil, err
+			}
+			writeCapacity, err := parseCapacity("DYNAMODB_WRITE_CAPACITY", cfg.DynamoDBWriteCapacity)
+			if err != nil {
+				return nil, err
+			}
+
+			return database.NewDynamoStore(ctx, database.DynamoConfig{
+				Endpoint:          cfg.DynamoDBEndpoint,
+				Region:            cfg.DynamoDBRegion,
+				Profile:           cfg.DynamoDBProfile,
+				Table:             cfg.DynamoDBTable,
+				EventsTable:       cfg.DynamoDBEventsTable,
+				ReservationsTable: cfg.DynamoDBReservationsTable,
+				WaitlistTable:     cfg.DynamoDBWaitlistTable,
+				BillingMode:       billingMode,
+				ReadCapacity:      readCapacity,
+				WriteCapacity:     writeCapacity,
+			})
 		},
 
 		store.ProviderJiraAssets: func(ctx context.Context, cfg store.StoreConfig) (store.Store, error) {
+			mapping, err := database.LoadJiraDeviceMapping(cfg.JiraMappingFile)
+			if err != nil {
+				return nil, err
+			}
+			mapping, err = mapping.WithAttributeIDs(cfg.JiraAttributeIDs)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.JiraObjectTypeID != "" {
+				mapping.ObjectTypeID = cfg.JiraObjectTypeID
+			}
+
 			return database.NewJiraAssetsClient(
+				ctx,
 				cfg.JiraBaseURL,
 				cfg.JiraEmail,
 				cfg.JiraToken,
+				mapping,
 			)
 		},
+
+		store.ProviderMemory: func(ctx context.Context, cfg store.StoreConfig) (store.Store, error) {
+			return database.NewMemoryStore(cfg.MemorySeedFile)
+		},
+
+		store.ProviderSQLite: func(ctx context.Context, cfg store.StoreConfig) (store.Store, error) {
+			path := cfg.SQLitePath
+			if path == "" {
+				path = "curator.db"
+			}
+			return database.NewSQLiteStore(ctx, path)
+		},
 	}
 
 	dbStore, err := store.NewStoreFactory(ctx, cfg, constructors)
 	if err != nil {
 		log.Fatalf("Failed to initialize database store: %v", err)
 	}
+
+	retryPolicy := store.DefaultRetryPolicy()
+	if cfg.RetryMaxAttempts != "" {
+		attempts, err := strconv.Atoi(cfg.RetryMaxAttempts)
+		if err != nil || attempts < 1 {
+			log.Fatalf("Invalid STORE_RETRY_ATTEMPTS %q: must be a positive integer", cfg.RetryMaxAttempts)
+		}
+		retryPolicy.MaxAttempts = attempts
+	}
+	if cfg.CallTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.CallTimeout)
+		if err != nil {
+			log.Fatalf("Invalid STORE_CALL_TIMEOUT %q: %v", cfg.CallTimeout, err)
+		}
+		retryPolicy.Timeout = timeout
+	}
+	dbStore = store.NewRetryingStore(dbStore, retryPolicy)
+
+	schemas, err := model.LoadSchemaRegistry(os.Getenv("CURATOR_SCHEMA_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load device type schemas: %v", err)
+	}
+	dbStore = store.NewValidatingStore(dbStore, schemas)
+
+	if cfg.CacheTTL != "" {
+		ttl, err := time.ParseDuration(cfg.CacheTTL)
+		if err != nil {
+			log.Fatalf("Invalid STORE_CACHE_TTL %q: %v", cfg.CacheTTL, err)
+		}
+		if ttl > 0 {
+			dbStore = store.NewCachingStore(dbStore, ttl)
+			log.Printf("Caching device reads for %s", ttl)
+		}
+	}
 	defer dbStore.Close()
 
 	log.Printf("Successfully initialized store using provider: %s", cfg.Provider)
@@ -79,17 +183,45 @@ func main() {
 		socketmode.OptionLog(log.New(os.Stdout, "socketmode: ", log.Lshortfile|log.LstdFlags)),
 	)
 
+	warrantyDays := 30
+	if value := os.Getenv("CURATOR_WARRANTY_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 1 {
+			log.Fatalf("Invalid CURATOR_WARRANTY_DAYS %q: must be a positive integer", value)
+		}
+		warrantyDays = days
+	}
+
+	waitlistHoldHours := 24
+	if value := os.Getenv("CURATOR_WAITLIST_HOLD_HOURS"); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil || hours < 1 {
+			log.Fatalf("Invalid CURATOR_WAITLIST_HOLD_HOURS %q: must be a positive integer", value)
+		}
+		waitlistHoldHours = hours
+	}
+
 	slackApp := &app.App{
 		API:    api,
 		Client: client,
 		DB:     dbStore,
+		Admins: strings.FieldsFunc(os.Getenv("CURATOR_ADMINS"), func(r rune) bool { return r == ',' || r == ' ' }),
+
+		WarrantyChannel: os.Getenv("CURATOR_WARRANTY_CHANNEL"),
+		WarrantyWindow:  time.Duration(warrantyDays) * 24 * time.Hour,
+
+		Schemas: schemas,
+
+		WaitlistHold: time.Duration(waitlistHoldHours) * time.Hour,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Start the background scheduler
+	// Start the background schedulers
 	go slackApp.StartOverdueChecker(ctx)
+	go slackApp.StartWarrantyDigest(ctx)
+	go slackApp.StartWaitlistChecker(ctx)
 
 	fmt.Println("Starting Socket Mode listener...")
 
@@ -99,3 +231,15 @@ func main() {
 		log.Fatalf("Socket Mode client failed: %v", err)
 	}
 }
+
+// parseCapacity parses an optional capacity unit setting; empty means the provider default.
+func parseCapacity(name, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, value)
+	}
+	return n, nil
+}
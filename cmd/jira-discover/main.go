@@ -0,0 +1,102 @@
+// Command jira-discover lists the Jira Assets object schemas, object types and
+// attributes visible to the configured credentials, and prints a starting
+// mapping file for the jira-api store provider (see JIRA_MAPPING_FILE).
+//
+// Usage:
+//
+//	jira-discover [-schema <id>] [-type <id or name>] > jira-mapping.json
+//
+// It reads JIRA_BASE_URL, JIRA_EMAIL and JIRA_TOKEN like the bot does.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"bdemetris/curator/pkg/database"
+	"bdemetris/curator/pkg/model"
+)
+
+func main() {
+	schemaID := flag.String("schema", "", "only inspect the object schema with this ID")
+	typeArg := flag.String("type", database.DefaultJiraDeviceMapping().ObjectType, "object type (ID or name) to generate a mapping for")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	client, err := database.NewJiraAssetsAdminClient(
+		os.Getenv("JIRA_BASE_URL"),
+		os.Getenv("JIRA_EMAIL"),
+		os.Getenv("JIRA_TOKEN"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create Jira Assets client: %v", err)
+	}
+
+	schemas, err := client.ListObjectSchemas(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list object schemas: %v", err)
+	}
+
+	// The listing goes to stderr so that stdout can be redirected straight into a mapping file.
+	var candidates []model.JiraObjectType
+	for _, schema := range schemas {
+		if *schemaID != "" && schema.ID != *schemaID {
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Schema %s: %s (%s)\n", schema.ID, schema.Name, schema.Key)
+
+		types, err := client.ListObjectTypes(ctx, schema.ID)
+		if err != nil {
+			log.Fatalf("Failed to list object types: %v", err)
+		}
+		for _, t := range types {
+			fmt.Fprintf(os.Stderr, "  Object type %s: %s\n", t.ID, t.Name)
+			if t.ID == *typeArg || strings.EqualFold(t.Name, *typeArg) {
+				candidates = append(candidates, t)
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		log.Fatalf("No object type matches %q. Re-run with -type set to one of the object types listed above.", *typeArg)
+	case 1:
+	default:
+		log.Fatalf("%d object types match %q. Re-run with -schema or -type set to an ID.", len(candidates), *typeArg)
+	}
+	objectType := candidates[0]
+
+	attrs, err := client.ListObjectTypeAttributes(ctx, objectType.ID)
+	if err != nil {
+		log.Fatalf("Failed to list attributes: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nAttributes of %s (%s):\n", objectType.Name, objectType.ID)
+	for _, attr := range attrs {
+		label := ""
+		if attr.Label {
+			label = " [label]"
+		}
+		fmt.Fprintf(os.Stderr, "  %-6s %-30s %s%s\n", attr.ID, attr.Name, attr.Type, label)
+	}
+
+	mapping := database.SuggestJiraDeviceMapping(objectType, attrs)
+
+	fmt.Fprintf(os.Stderr, "\nSuggested mapping (%d fields matched by name). Review it, then set JIRA_MAPPING_FILE.\n", len(mapping.Fields))
+	if _, ok := mapping.Fields[database.FieldAssetTag]; !ok {
+		fmt.Fprintln(os.Stderr, "Note: AssetTag is unmapped, so the object key will be used as the asset tag.")
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(mapping); err != nil {
+		log.Fatalf("Failed to write mapping: %v", err)
+	}
+}
@@ -1,5 +1,7 @@
 import boto3
 import csv
+from datetime import datetime, timezone
+from decimal import Decimal
 
 # Configuration
 TABLE_NAME = 'Devices'
@@ -7,6 +9,46 @@ CSV_FILE_PATH = 'devices.csv'
 LOCAL_ENDPOINT = 'http://localhost:8000'
 PARTITION_KEY = 'AssetTag'  # Replace with your actual ID column name
 
+# Columns the app reads as something other than a string.
+DATE_COLUMNS = {'PurchaseDate', 'WarrantyExpiration'}  # YYYY-MM-DD or RFC 3339
+CENTS_COLUMNS = {'PurchaseCost'}  # A decimal amount such as 1,299.00, stored in cents
+ATTRIBUTE_PREFIX = 'Attributes.'  # Custom attribute columns, e.g. Attributes.carrier
+
+# The app's secondary indexes, keyed on the assignee in lower case and on the due date
+# within a single partition. Due dates are stored as fixed-width UTC so they compare as strings.
+ASSIGNEE_KEY = 'AssigneeKey'
+DUE_SHARD_KEY, DUE_SHARD = 'DueShard', 'due'
+
+def parse_date(value):
+    parsed = datetime.fromisoformat(value.replace('Z', '+00:00'))
+    if parsed.tzinfo is None:
+        parsed = parsed.replace(tzinfo=timezone.utc)
+    return parsed
+
+def convert_typed_columns(item):
+    for column in DATE_COLUMNS & item.keys():
+        value = item.pop(column)
+        if value:
+            item[column] = parse_date(value).isoformat().replace('+00:00', 'Z')
+    if item.get('DueDate'):
+        due = parse_date(item['DueDate']).astimezone(timezone.utc)
+        item['DueDate'] = due.strftime('%Y-%m-%dT%H:%M:%S.%f000Z')
+        item[DUE_SHARD_KEY] = DUE_SHARD
+    if item.get('AssignedTo'):
+        item[ASSIGNEE_KEY] = item['AssignedTo'].lower()
+    for column in CENTS_COLUMNS & item.keys():
+        value = item.pop(column).replace(',', '').lstrip('$')
+        if value:
+            item[column] = int(Decimal(value) * 100)
+    attributes = {}
+    for column in [c for c in item if c.startswith(ATTRIBUTE_PREFIX)]:
+        value = item.pop(column)
+        if value:
+            attributes[column[len(ATTRIBUTE_PREFIX):].lower()] = value
+    if attributes:
+        item['Attributes'] = attributes
+    return item
+
 def import_all_as_strings():
     # Connect to local DynamoDB
     dynamodb = boto3.resource(
@@ -26,11 +68,12 @@ def import_all_as_strings():
         with table.batch_writer() as batch:
             for line_number, row in enumerate(reader, start=1):
                 # 1. Force every key and value to be a string and strip whitespace
-                # Filter out empty keys (fixes 'Empty attribute name' error)
+                # Filter out empty keys (fixes 'Empty attribute name' error) and empty
+                # cells: DynamoDB rejects empty strings in index keys such as AssignedTo
                 item = {
-                    str(k).strip(): str(v).strip() 
-                    for k, v in row.items() 
-                    if k and k.strip()
+                    str(k).strip(): str(v).strip()
+                    for k, v in row.items()
+                    if k and k.strip() and v is not None and str(v).strip()
                 }
 
                 # 2. VALIDATION: Ensure the Partition Key exists
@@ -40,11 +83,11 @@ def import_all_as_strings():
 
                 # 3. Write to DynamoDB
                 try:
-                    batch.put_item(Item=item)
+                    batch.put_item(Item=convert_typed_columns(item))
                 except Exception as e:
                     print(f"Line {line_number}: Failed to insert. Error: {e}")
                     
-    print("Done! All data has been sent as strings.")
+    print("Done! All data has been sent.")
 
 if __name__ == "__main__":
     import_all_as_strings()
\ No newline at end of file
@@ -2,9 +2,12 @@ package app
 
 import (
 	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -12,42 +15,70 @@ import (
 
 func (a *App) handleShowDevices(ctx context.Context, channelID, userID string, args []string) {
 	if len(args) == 0 {
-		a.sendText(channelID, "Usage: `@bot show <all | mine | available | AssetTag>`")
+		a.sendText(channelID, "Usage: `@bot show <all | mine | available | retired | in-repair | lost | reserved | AssetTag> [at YYYY-MM-DD]`")
 		return
 	}
 
-	firstArg := strings.ToLower(strings.TrimSpace(args[0]))
-
-	allDevices, err := a.DB.ListDevices(ctx)
-	if err != nil {
-		log.Printf("DB Error: %v", err)
-		a.sendText(channelID, "❌ Error retrieving devices.")
+	if len(args) == 3 && args[1] == "at" {
+		a.handleShowDevicesAt(ctx, channelID, args[0], args[2])
 		return
 	}
 
+	firstArg := strings.ToLower(strings.TrimSpace(args[0]))
+
 	var filtered []model.Device
 	var title string
 
 	switch firstArg {
 	case "all":
+		allDevices, ok := a.queryDevices(ctx, channelID, &store.DeviceFilter{})
+		if !ok {
+			return
+		}
 		filtered = allDevices
 		title = "All Devices"
 
+	case "reserved":
+		reserved, ok := a.reservedDevices(ctx, channelID)
+		if !ok {
+			return
+		}
+		filtered = reserved
+		title = "Reserved Devices"
+
+	case "retired", "in-repair", "repair", "lost":
+		status, _ := model.ParseDeviceStatus(firstArg)
+		devices, ok := a.queryDevices(ctx, channelID, &store.DeviceFilter{Status: status})
+		if !ok {
+			return
+		}
+		filtered = devices
+		title = titleCase(status.String()) + " Devices"
+
 	case "mine":
-		user, _ := a.API.GetUserInfo(userID)
-		email := strings.ToLower(strings.TrimSpace(user.Profile.Email))
-		for _, d := range allDevices {
-			if strings.ToLower(strings.TrimSpace(d.AssignedTo)) == email && email != "" {
-				filtered = append(filtered, d)
+		identity, ok := a.lookupUserIdentity(channelID, userID)
+		if !ok {
+			return
+		}
+		if identity != "" {
+			mine, ok := a.queryDevices(ctx, channelID, &store.DeviceFilter{AssignedTo: identity})
+			if !ok {
+				return
 			}
+			filtered = mine
 		}
 		title = "Your Checked-out Devices"
 
 	case "types":
+		allDevices, ok := a.queryDevices(ctx, channelID, &store.DeviceFilter{})
+		if !ok {
+			return
+		}
+
 		typeMap := make(map[string]int)
 		for _, d := range allDevices {
 			if d.DeviceType != "" {
-				t := strings.Title(strings.ToLower(strings.TrimSpace(d.DeviceType)))
+				t := titleCase(strings.ToLower(d.DeviceType))
 				typeMap[t]++
 			}
 		}
@@ -71,37 +102,78 @@ func (a *App) handleShowDevices(ctx context.Context, channelID, userID string, a
 		return
 
 	case "available":
-		filterText := ""
-		if len(args) > 1 {
-			filterText = strings.ToLower(strings.TrimSpace(strings.Join(args[1:], " ")))
+		// key=value terms match custom attributes, e.g. "show available phone carrier=verizon";
+		// the rest is free text.
+		filter := store.DeviceFilter{Availability: store.OnlyAvailable}
+		var terms []string
+		for _, arg := range args[1:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if ok && key != "" && value != "" {
+				if filter.Attributes == nil {
+					filter.Attributes = make(map[string]string)
+				}
+				filter.Attributes[key] = value
+				continue
+			}
+			terms = append(terms, arg)
 		}
+		filterText := strings.ToLower(strings.TrimSpace(strings.Join(terms, " ")))
 
-		for _, d := range allDevices {
-			if d.AssignedTo == "" {
-				dbModel := strings.ToLower(d.DeviceModel)
-				dbType := strings.ToLower(d.DeviceType)
-				dbAssetTag := strings.ToLower(d.AssetTag)
-
-				if filterText == "" ||
-					strings.Contains(dbModel, filterText) ||
-					strings.Contains(dbType, filterText) ||
-					strings.Contains(dbAssetTag, filterText) {
-					filtered = append(filtered, d)
-				}
+		available, ok := a.queryDevices(ctx, channelID, &filter)
+		if !ok {
+			return
+		}
+
+		// Devices reserved for right now stay available in the store until the holder
+		// checks them out, but nobody else can take them.
+		reserved, err := a.reservedNow(ctx)
+		if err != nil {
+			log.Printf("DB Error (Reservations): %v", err)
+			a.replyStoreError(ctx, channelID, "retrieve devices", err)
+			return
+		}
+
+		for _, d := range available {
+			if _, ok := reserved[d.AssetTag]; ok {
+				continue
+			}
+			if deviceMatchesText(d, filterText) {
+				filtered = append(filtered, d)
 			}
 		}
 		title = "Available Devices"
-		if filterText != "" {
-			title += fmt.Sprintf(" (Filter: '%s')", filterText)
+		if len(args) > 1 {
+			title += fmt.Sprintf(" (Filter: '%s')", strings.Join(args[1:], " "))
+		}
+		if len(filtered) == 0 && filterText != "" && len(filter.Attributes) == 0 {
+			a.sendText(channelID, fmt.Sprintf("No devices found for: *%s*\n⏳ Run `@bot waitlist %s` to get the next one that comes back.", title, filterText))
+			return
 		}
 
 	default:
+		title = fmt.Sprintf("Lookup Asset Tag: %s", args[0])
+
+		device, err := a.DB.GetDevice(ctx, args[0])
+		if err == nil {
+			filtered = append(filtered, device)
+			break
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("DB Error: %v", err)
+			a.replyStoreError(ctx, channelID, "retrieve devices", err)
+			return
+		}
+
+		// Asset tags are case-sensitive in the store but not in chat, so fall back to a full comparison.
+		allDevices, ok := a.queryDevices(ctx, channelID, nil)
+		if !ok {
+			return
+		}
 		for _, d := range allDevices {
 			if strings.ToLower(strings.TrimSpace(d.AssetTag)) == firstArg {
 				filtered = append(filtered, d)
 			}
 		}
-		title = fmt.Sprintf("Lookup Asset Tag: %s", args[0])
 	}
 
 	if len(filtered) == 0 {
@@ -117,19 +189,430 @@ func (a *App) handleShowDevices(ctx context.Context, channelID, userID string, a
 	a.renderDeviceTable(channelID, title, filtered)
 }
 
+// deviceMatchesText reports whether the device's model, type, asset tag or a custom
+// attribute value contains text, ignoring case. Empty text matches every device.
+func deviceMatchesText(d model.Device, text string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	return text == "" ||
+		strings.Contains(strings.ToLower(d.DeviceModel), text) ||
+		strings.Contains(strings.ToLower(d.DeviceType), text) ||
+		strings.Contains(strings.ToLower(d.AssetTag), text) ||
+		attributesContain(d, text)
+}
+
+// attributesContain reports whether any custom attribute value of the device contains
+// text, which must be lower case.
+func attributesContain(d model.Device, text string) bool {
+	for _, value := range d.Attributes {
+		if strings.Contains(strings.ToLower(value), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleShowDevicesAt shows a device, or the whole inventory for "all", as it was at the
+// given date by replaying device history.
+func (a *App) handleShowDevicesAt(ctx context.Context, channelID, target, date string) {
+	at, err := ParsePointInTime(date)
+	if err != nil {
+		a.sendText(channelID, "Usage: `@bot show <all | AssetTag> at <YYYY-MM-DD>`")
+		return
+	}
+	label := at.Format("Jan 02, 2006 15:04 MST")
+
+	if strings.ToLower(target) == "all" {
+		devices, err := store.InventoryAsOf(ctx, a.DB, at)
+		if err != nil {
+			log.Printf("DB Error (Inventory at %s): %v", date, err)
+			a.replyStoreError(ctx, channelID, "rebuild the inventory", err)
+			return
+		}
+
+		title := fmt.Sprintf("Inventory as of %s", label)
+		if len(devices) == 0 {
+			a.sendText(channelID, fmt.Sprintf("No devices found for: *%s*", title))
+			return
+		}
+		a.renderDeviceTable(channelID, title, devices)
+		return
+	}
+
+	assetTag, ok := a.resolveRecordedAssetTag(ctx, channelID, target)
+	if !ok {
+		return
+	}
+
+	device, existed, err := store.DeviceAsOf(ctx, a.DB, assetTag, at)
+	if err != nil {
+		log.Printf("DB Error (Show %s at %s): %v", assetTag, date, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("rebuild `%s`", assetTag), err)
+		return
+	}
+	if !existed {
+		a.sendText(channelID, fmt.Sprintf("ℹ️ Device `%s` was not in the inventory at %s.", assetTag, label))
+		return
+	}
+
+	a.sendText(channelID, fmt.Sprintf("🕘 Device `%s` as of %s:", assetTag, label))
+	a.renderSingleDeviceDetail(channelID, device)
+}
+
+// queryDevices runs a filtered query, or lists every device when filter is nil.
+// It replies in the channel and returns false on error.
+func (a *App) queryDevices(ctx context.Context, channelID string, filter *store.DeviceFilter) ([]model.Device, bool) {
+	var devices []model.Device
+	var err error
+	if filter == nil {
+		devices, err = a.DB.ListDevices(ctx)
+	} else {
+		devices, err = a.DB.QueryDevices(ctx, *filter)
+	}
+	if err != nil {
+		log.Printf("DB Error: %v", err)
+		a.replyStoreError(ctx, channelID, "retrieve devices", err)
+		return nil, false
+	}
+	return devices, true
+}
+
 func (a *App) handleCheckoutDevice(ctx context.Context, channelID, userID string, args []string) {
 	if len(args) != 1 {
 		a.sendText(channelID, "Usage: `@bot checkout <AssetTag>`")
 		return
 	}
 
-	serial := args[0]
+	assetTag, ok := a.resolveAssetTag(ctx, channelID, args[0])
+	if !ok {
+		return
+	}
+
+	userEmail, ok := a.lookupUserIdentity(channelID, userID)
+	if !ok {
+		return
+	}
+
+	ctx = store.WithActor(ctx, userEmail)
+	now := time.Now()
+
+	// The store checks reservations again as part of the checkout, and refuses it if a
+	// reservation made in between overlaps the loan.
+	due, next, ok := a.checkoutDueDate(ctx, channelID, assetTag, userEmail, now, now.AddDate(0, 0, 30))
+	if !ok {
+		return
+	}
+
+	if err := a.DB.CheckoutDevice(ctx, assetTag, userEmail, now, due); err != nil {
+		log.Printf("DB Update Error (Checkout %s by %s): %v", assetTag, userEmail, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("checkout device `%s`", assetTag), err)
+		return
+	}
+
+	msg := fmt.Sprintf("✅ Device `%s` checked out to *%s*.\n📅 *Due back:* %s",
+		assetTag, userEmail, due.Format("Jan 02, 2006"))
+	if next != nil {
+		msg += fmt.Sprintf("\n📌 It is reserved by *%s* %s, so it's due back before then.", next.ReservedBy, reservationSpan(*next))
+	}
+	a.sendText(channelID, msg)
+
+	if device, err := a.DB.GetDevice(ctx, assetTag); err == nil {
+		a.settleWaitlist(ctx, userEmail, device)
+	} else {
+		log.Printf("DB Error (Waitlist after checkout of %s): %v", assetTag, err)
+	}
+}
+
+func (a *App) handleReturnDevice(ctx context.Context, channelID, userID string, args []string) {
+	if len(args) != 1 {
+		a.sendText(channelID, "Usage: `@bot return <AssetTag>`")
+		return
+	}
+
+	assetTag, ok := a.resolveAssetTag(ctx, channelID, args[0])
+	if !ok {
+		return
+	}
+
+	userEmail, ok := a.lookupUserIdentity(channelID, userID)
+	if !ok {
+		return
+	}
+
+	ctx = store.WithActor(ctx, userEmail)
+	if err := a.DB.ReturnDevice(ctx, assetTag, userEmail); err != nil {
+		var conflict *store.ConflictError
+		if errors.As(err, &conflict) && conflict.AssignedTo != "" {
+			a.sendText(channelID, fmt.Sprintf("❌ Device `%s` is checked out to *%s*, not you.", assetTag, conflict.AssignedTo))
+			return
+		}
+		log.Printf("DB Update Error (Return %s by %s): %v", assetTag, userEmail, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("return device `%s`", assetTag), err)
+		return
+	}
+
+	a.sendText(channelID, fmt.Sprintf("✅ Device `%s` returned. Thanks!", assetTag))
+	a.offerDevice(ctx, assetTag)
+}
+
+func (a *App) handleDeviceHistory(ctx context.Context, channelID string, args []string) {
+	if len(args) != 1 {
+		a.sendText(channelID, "Usage: `@bot history <AssetTag>`")
+		return
+	}
+
+	assetTag, ok := a.resolveRecordedAssetTag(ctx, channelID, args[0])
+	if !ok {
+		return
+	}
+
+	events, err := a.DB.ListDeviceEvents(ctx, assetTag)
+	if err != nil {
+		log.Printf("DB Error (History %s): %v", assetTag, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("load the history of `%s`", assetTag), err)
+		return
+	}
+
+	if len(events) == 0 {
+		a.sendText(channelID, fmt.Sprintf("No history recorded for `%s` yet.", assetTag))
+		return
+	}
+
+	a.renderDeviceHistory(channelID, assetTag, events)
+}
+
+func (a *App) handleRetireDevice(ctx context.Context, channelID, userID string, args []string) {
+	if !a.isAdmin(userID) {
+		a.sendText(channelID, "⛔ Only inventory admins can retire devices.")
+		return
+	}
+	if len(args) != 1 {
+		a.sendText(channelID, "Usage: `@bot retire <AssetTag>`")
+		return
+	}
+
+	if assetTag, ok := a.setDeviceStatus(ctx, channelID, userID, args[0], model.StatusRetired); ok {
+		a.sendText(channelID, fmt.Sprintf("🗄️ Device `%s` retired. It keeps its history but can no longer be checked out.", assetTag))
+	}
+}
+
+func (a *App) handleSetDeviceStatus(ctx context.Context, channelID, userID string, args []string) {
+	if !a.isAdmin(userID) {
+		a.sendText(channelID, "⛔ Only inventory admins can change device statuses.")
+		return
+	}
+	usage := "Usage: `@bot status <AssetTag> <available | in-repair | lost | retired>`"
+	if len(args) < 2 {
+		a.sendText(channelID, usage)
+		return
+	}
+
+	status, err := model.ParseDeviceStatus(strings.Join(args[1:], " "))
+	if err != nil {
+		a.sendText(channelID, usage)
+		return
+	}
+	if status == model.StatusCheckedOut {
+		a.sendText(channelID, fmt.Sprintf("ℹ️ Use `@bot checkout %s` to check a device out.", args[0]))
+		return
+	}
+
+	if assetTag, ok := a.setDeviceStatus(ctx, channelID, userID, args[0], status); ok {
+		a.sendText(channelID, fmt.Sprintf("✅ Device `%s` is now *%s*.", assetTag, status))
+		if status == model.StatusAvailable {
+			a.offerDevice(ctx, assetTag)
+		}
+	}
+}
+
+// setDeviceStatus moves the device with the given tag to status on behalf of the user,
+// replying in the channel if it fails.
+func (a *App) setDeviceStatus(ctx context.Context, channelID, userID, tag string, status model.DeviceStatus) (string, bool) {
+	assetTag, ok := a.resolveAssetTag(ctx, channelID, tag)
+	if !ok {
+		return "", false
+	}
+
+	userEmail, ok := a.lookupUserIdentity(channelID, userID)
+	if !ok {
+		return "", false
+	}
+
+	ctx = store.WithActor(ctx, userEmail)
+	if err := a.DB.SetDeviceStatus(ctx, assetTag, status); err != nil {
+		log.Printf("DB Update Error (Status %s to %s by %s): %v", assetTag, status, userEmail, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("mark device `%s` as %s", assetTag, status), err)
+		return "", false
+	}
+	return assetTag, true
+}
+
+func (a *App) handleDeleteDevice(ctx context.Context, channelID, userID string, args []string) {
+	if !a.isAdmin(userID) {
+		a.sendText(channelID, "⛔ Only inventory admins can delete devices.")
+		return
+	}
+	if len(args) == 0 || len(args) > 2 {
+		a.sendText(channelID, "Usage: `@bot delete <AssetTag> confirm`")
+		return
+	}
+
+	assetTag, ok := a.resolveAssetTag(ctx, channelID, args[0])
+	if !ok {
+		return
+	}
+
+	// Deleting cannot be undone, so it has to be asked for twice.
+	if len(args) != 2 || args[1] != "confirm" {
+		a.sendText(channelID, fmt.Sprintf("⚠️ Deleting `%s` removes it from the inventory for good. Consider `@bot retire %s` instead, or run `@bot delete %s confirm` to go ahead.",
+			assetTag, args[0], args[0]))
+		return
+	}
+
+	userEmail, ok := a.lookupUserIdentity(channelID, userID)
+	if !ok {
+		return
+	}
 
+	ctx = store.WithActor(ctx, userEmail)
+	err := a.DB.DeleteDevice(ctx, assetTag)
+	if errors.Is(err, store.ErrUnsupported) {
+		a.sendText(channelID, fmt.Sprintf("⚠️ This inventory can't delete devices without losing their history. Use `@bot retire %s` instead.", args[0]))
+		return
+	}
+	if err != nil {
+		log.Printf("DB Update Error (Delete %s by %s): %v", assetTag, userEmail, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("delete device `%s`", assetTag), err)
+		return
+	}
+
+	a.sendText(channelID, fmt.Sprintf("🗑️ Device `%s` deleted.", assetTag))
+}
+
+// resolveAssetTag returns the stored asset tag matching tag, which may differ in case
+// since commands are lower-cased. It replies in the channel if there is no such device.
+func (a *App) resolveAssetTag(ctx context.Context, channelID, tag string) (string, bool) {
+	return a.resolveTag(ctx, channelID, tag, false)
+}
+
+// resolveRecordedAssetTag is like resolveAssetTag, but also finds deleted devices by the
+// history they leave behind. Only current devices can be matched ignoring case, so a
+// deleted device is looked up by tag as typed and in upper case.
+func (a *App) resolveRecordedAssetTag(ctx context.Context, channelID, tag string) (string, bool) {
+	return a.resolveTag(ctx, channelID, tag, true)
+}
+
+func (a *App) resolveTag(ctx context.Context, channelID, tag string, includeDeleted bool) (string, bool) {
+	device, err := a.DB.GetDevice(ctx, tag)
+	if err == nil {
+		return device.AssetTag, true
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		log.Printf("DB Error: %v", err)
+		a.replyStoreError(ctx, channelID, "retrieve devices", err)
+		return "", false
+	}
+
+	allDevices, ok := a.queryDevices(ctx, channelID, nil)
+	if !ok {
+		return "", false
+	}
+	for _, d := range allDevices {
+		if strings.EqualFold(strings.TrimSpace(d.AssetTag), tag) {
+			return d.AssetTag, true
+		}
+	}
+
+	if includeDeleted {
+		for _, candidate := range slices.Compact([]string{tag, strings.ToUpper(tag)}) {
+			events, err := a.DB.ListDeviceEvents(ctx, candidate)
+			if err != nil {
+				log.Printf("DB Error: %v", err)
+				a.replyStoreError(ctx, channelID, "retrieve devices", err)
+				return "", false
+			}
+			if len(events) > 0 {
+				return candidate, true
+			}
+		}
+	}
+
+	a.sendText(channelID, a.unknownAssetTagMessage(ctx, tag))
+	return "", false
+}
+
+// replyStoreError turns an error from the store into a specific reply. The raw error is
+// never shown to users; callers are expected to log it. action completes the sentence
+// "Failed to ...".
+func (a *App) replyStoreError(ctx context.Context, channelID, action string, err error) {
+	var notFound *store.NotFoundError
+	var conflict *store.ConflictError
+	var transition *store.TransitionError
+	var reservationConflict *store.ReservationConflictError
+	var reservationNotFound *store.ReservationNotFoundError
+
+	switch {
+	case errors.As(err, &notFound):
+		a.sendText(channelID, a.unknownAssetTagMessage(ctx, notFound.AssetTag))
+	case errors.As(err, &reservationNotFound):
+		a.sendText(channelID, fmt.Sprintf("❌ Device `%s` has no reservation `%s`.", reservationNotFound.AssetTag, reservationNotFound.ReservationID))
+	case errors.As(err, &reservationConflict):
+		a.sendText(channelID, reservationConflictMessage(reservationConflict))
+	case errors.As(err, &transition):
+		switch {
+		case transition.From == model.StatusRetired:
+			a.sendText(channelID, fmt.Sprintf("🗄️ Device `%s` has been retired and can no longer be checked out or changed.", transition.AssetTag))
+		case transition.To == model.StatusCheckedOut:
+			a.sendText(channelID, fmt.Sprintf("🚫 Device `%s` is %s and can't be checked out right now.", transition.AssetTag, transition.From))
+		default:
+			a.sendText(channelID, fmt.Sprintf("🚫 Device `%s` is %s and can't be marked %s.", transition.AssetTag, transition.From, transition.To))
+		}
+	case errors.As(err, &conflict):
+		switch {
+		case conflict.AssignedTo == "":
+			a.sendText(channelID, fmt.Sprintf("ℹ️ Device `%s` is not checked out.", conflict.AssetTag))
+		default:
+			a.sendText(channelID, checkoutConflictMessage(conflict))
+		}
+	case errors.Is(err, store.ErrUnsupported):
+		a.sendText(channelID, fmt.Sprintf("⚠️ Failed to %s: the inventory backend doesn't support this yet. Please contact an admin.", action))
+	case errors.Is(err, store.ErrTransient):
+		a.sendText(channelID, fmt.Sprintf("⏳ Failed to %s: the inventory is busy right now. Please try again in a minute.", action))
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		a.sendText(channelID, fmt.Sprintf("⏳ Failed to %s: the inventory took too long to respond. Please try again.", action))
+	default:
+		a.sendText(channelID, fmt.Sprintf("❌ Failed to %s due to an unexpected error. Please try again later.", action))
+	}
+}
+
+// unknownAssetTagMessage explains that an asset tag does not exist, suggesting the closest existing tags.
+func (a *App) unknownAssetTagMessage(ctx context.Context, assetTag string) string {
+	msg := fmt.Sprintf("❌ No device found with asset tag `%s`.", assetTag)
+
+	devices, err := a.DB.ListDevices(ctx)
+	if err != nil {
+		log.Printf("DB Error: %v", err)
+		return msg
+	}
+
+	tags := make([]string, 0, len(devices))
+	for _, d := range devices {
+		tags = append(tags, d.AssetTag)
+	}
+
+	if suggestions := ClosestMatches(assetTag, tags, 3); len(suggestions) > 0 {
+		msg += fmt.Sprintf(" Did you mean `%s`?", strings.Join(suggestions, "`, `"))
+	}
+	return msg
+}
+
+// lookupUserIdentity returns the identity devices are assigned to for a Slack user: their
+// email, falling back to display or real name. It replies in the channel on failure.
+func (a *App) lookupUserIdentity(channelID, userID string) (string, bool) {
 	user, err := a.API.GetUserInfo(userID)
 	if err != nil {
 		log.Printf("Slack API Error (GetUserInfo for %s): %v", userID, err)
 		a.sendText(channelID, "❌ Failed to retrieve your user profile from Slack.")
-		return
+		return "", false
 	}
 
 	userEmail := user.Profile.Email
@@ -140,21 +623,14 @@ func (a *App) handleCheckoutDevice(ctx context.Context, channelID, userID string
 		}
 		log.Printf("Warning: No email found for user %s, using fallback: %s", userID, userEmail)
 	}
+	return userEmail, true
+}
 
-	updates := make(map[string]interface{})
-	now := time.Now()
-	due := now.AddDate(0, 0, 30)
-
-	updates["AssignedTo"] = userEmail
-	updates["AssignedDate"] = &now
-	updates["DueDate"] = &due
-
-	if err := a.DB.UpdateDevice(ctx, serial, updates); err != nil {
-		log.Printf("DB Update Error (Checkout %s by %s): %v", serial, userEmail, err)
-		a.sendText(channelID, fmt.Sprintf("❌ Failed to checkout device `%s`: %v", serial, err))
-		return
+// checkoutConflictMessage tells the user who currently holds a device.
+func checkoutConflictMessage(conflict *store.ConflictError) string {
+	msg := fmt.Sprintf("❌ Device `%s` is already checked out to *%s*.", conflict.AssetTag, conflict.AssignedTo)
+	if conflict.DueDate != nil {
+		msg += fmt.Sprintf("\n📅 *Due back:* %s", conflict.DueDate.Format("Jan 02, 2006"))
 	}
-
-	a.sendText(channelID, fmt.Sprintf("✅ Device `%s` checked out to *%s*.\n📅 *Due back:* %s",
-		serial, userEmail, due.Format("Jan 02, 2006")))
+	return msg
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
+	"time"
 
+	"bdemetris/curator/pkg/model"
 	"bdemetris/curator/pkg/store"
 
 	"github.com/slack-go/slack"
@@ -18,6 +21,19 @@ type App struct {
 	API    *slack.Client
 	Client *socketmode.Client
 	DB     store.Store
+	Admins []string // Slack user IDs allowed to run admin commands such as retire and delete
+
+	WarrantyChannel string        // Slack channel for warranty expiry digests; empty disables them
+	WarrantyWindow  time.Duration // How far ahead the digest looks for expiring warranties
+
+	Schemas *model.SchemaRegistry // Labels and units of custom attributes; nil shows their keys
+
+	WaitlistHold time.Duration // How long a returned device is held for the next person on its waitlist
+}
+
+// isAdmin reports whether the Slack user may run admin commands.
+func (a *App) isAdmin(userID string) bool {
+	return slices.Contains(a.Admins, userID)
 }
 
 // HandleEvents listens for and processes incoming Slack events.
@@ -78,6 +94,22 @@ func (a *App) handleAppMentionCommand(ctx context.Context, channelID, userID, co
 		a.handleShowDevices(ctx, channelID, userID, args)
 	case "checkout":
 		a.handleCheckoutDevice(ctx, channelID, userID, args)
+	case "return":
+		a.handleReturnDevice(ctx, channelID, userID, args)
+	case "history":
+		a.handleDeviceHistory(ctx, channelID, args)
+	case "reserve":
+		a.handleReserveDevice(ctx, channelID, userID, args)
+	case "reservations":
+		a.handleReservations(ctx, channelID, userID, args)
+	case "waitlist":
+		a.handleWaitlist(ctx, channelID, userID, args)
+	case "status":
+		a.handleSetDeviceStatus(ctx, channelID, userID, args)
+	case "retire":
+		a.handleRetireDevice(ctx, channelID, userID, args)
+	case "delete":
+		a.handleDeleteDevice(ctx, channelID, userID, args)
 	default:
 		a.sendBlocks(channelID, createUnknownCommandMessage(userID))
 	}
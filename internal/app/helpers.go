@@ -1,6 +1,11 @@
 package app
 
-import "strings"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
 
 func IsArgumentAccepted(accepted []string, arg string) bool {
 	lowerArg := strings.ToLower(arg)
@@ -11,3 +16,75 @@ func IsArgumentAccepted(accepted []string, arg string) bool {
 	}
 	return false
 }
+
+// ClosestMatches returns up to limit candidates that are similar to target, closest first.
+// Comparison ignores case; a candidate qualifies if it contains the target or is within a
+// small edit distance of it.
+func ClosestMatches(target string, candidates []string, limit int) []string {
+	type scored struct {
+		value    string
+		distance int
+	}
+
+	target = strings.ToLower(strings.TrimSpace(target))
+	maxDistance := max(2, len(target)/3)
+
+	var matches []scored
+	for _, c := range candidates {
+		lower := strings.ToLower(c)
+		d := levenshtein(target, lower)
+		if d <= maxDistance || (target != "" && strings.Contains(lower, target)) {
+			matches = append(matches, scored{value: c, distance: d})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].value < matches[j].value
+	})
+
+	var result []string
+	for i := 0; i < len(matches) && i < limit; i++ {
+		result = append(result, matches[i].value)
+	}
+	return result
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// ParsePointInTime parses the timestamp of a point-in-time query. A date (2006-01-02)
+// means the end of that day in UTC; an RFC 3339 timestamp is used as is.
+func ParsePointInTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	// Commands are lower-cased before they reach the handlers.
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(value)); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestClosestMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		candidates []string
+		limit      int
+		want       []string
+	}{
+		{"exact match ignoring case first", "a-1234", []string{"B-1234", "A-1234"}, 3, []string{"A-1234", "B-1234"}},
+		{"transposed digits", "a-1243", []string{"A-1234", "Z-9999"}, 3, []string{"A-1234"}},
+		{"contains the target", "mac", []string{"ThinkPad-02", "MacBook-01"}, 3, []string{"MacBook-01"}},
+		{"ties sorted by value", "a-5", []string{"A-7", "A-6"}, 3, []string{"A-6", "A-7"}},
+		{"limited", "a-1", []string{"A-3", "A-2", "A-1"}, 2, []string{"A-1", "A-2"}},
+		{"nothing close", "zzz", []string{"A-1234"}, 3, nil},
+		{"no candidates", "a-1", nil, 3, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClosestMatches(tt.target, tt.candidates, tt.limit); !slices.Equal(got, tt.want) {
+				t.Errorf("ClosestMatches(%q, %q, %d) = %q, want %q", tt.target, tt.candidates, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePointInTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-03-31", want: time.Date(2024, 3, 31, 23, 59, 59, 999999999, time.UTC)},
+		{value: " 2024-03-31 ", want: time.Date(2024, 3, 31, 23, 59, 59, 999999999, time.UTC)},
+		{value: "2024-03-31t12:00:00z", want: time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)},
+		{value: "2024-03-31T12:00:00+02:00", want: time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-02-30", wantErr: true},
+		{value: "yesterday", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePointInTime(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePointInTime(%q) = %s, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParsePointInTime(%q) = %s, %v; want %s", tt.value, got, err, tt.want)
+		}
+	}
+}
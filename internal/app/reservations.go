@@ -0,0 +1,254 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+// reservationDayLayout is how reservation days are shown, e.g. "Tue, Oct 20".
+const reservationDayLayout = "Mon, Jan 02"
+
+func (a *App) handleReserveDevice(ctx context.Context, channelID, userID string, args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		a.sendText(channelID, "Usage: `@bot reserve <AssetTag> <YYYY-MM-DD> [YYYY-MM-DD]`")
+		return
+	}
+
+	// Reservations cover whole days in the bot's time zone; the end date is included.
+	from, err := time.ParseInLocation(time.DateOnly, args[1], time.Local)
+	if err != nil {
+		a.sendText(channelID, fmt.Sprintf("❌ Invalid date `%s`, expected YYYY-MM-DD.", args[1]))
+		return
+	}
+	to := from
+	if len(args) == 3 {
+		if to, err = time.ParseInLocation(time.DateOnly, args[2], time.Local); err != nil {
+			a.sendText(channelID, fmt.Sprintf("❌ Invalid date `%s`, expected YYYY-MM-DD.", args[2]))
+			return
+		}
+	}
+	if to.Before(from) {
+		a.sendText(channelID, "❌ The reservation has to end on or after the day it starts.")
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+	if !end.After(time.Now()) {
+		a.sendText(channelID, "❌ That reservation would already be over. Pick a day from today on.")
+		return
+	}
+
+	assetTag, ok := a.resolveAssetTag(ctx, channelID, args[0])
+	if !ok {
+		return
+	}
+	userEmail, ok := a.lookupUserIdentity(channelID, userID)
+	if !ok {
+		return
+	}
+
+	ctx = store.WithActor(ctx, userEmail)
+	r := store.NewReservation(assetTag, userEmail, from, end)
+	if err := a.DB.CreateReservation(ctx, r); err != nil {
+		log.Printf("DB Update Error (Reserve %s by %s): %v", assetTag, userEmail, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("reserve device `%s`", assetTag), err)
+		return
+	}
+
+	a.sendText(channelID, fmt.Sprintf("📌 Device `%s` is reserved for *%s* %s.\nReservation ID: `%s`. Cancel it with `@bot reservations cancel %s`.",
+		assetTag, userEmail, reservationSpan(r), r.ReservationID, r.ReservationID))
+}
+
+// handleReservations lists upcoming reservations: the user's own, or a device's when
+// given an asset tag. "reservations cancel <ID>" cancels one.
+func (a *App) handleReservations(ctx context.Context, channelID, userID string, args []string) {
+	if len(args) > 0 && args[0] == "cancel" {
+		a.handleCancelReservation(ctx, channelID, userID, args[1:])
+		return
+	}
+	if len(args) > 1 {
+		a.sendText(channelID, "Usage: `@bot reservations [AssetTag]` or `@bot reservations cancel <ReservationID>`")
+		return
+	}
+
+	now := time.Now()
+	filter := store.ReservationFilter{From: &now}
+	title := "Your Upcoming Reservations"
+	if len(args) == 1 {
+		assetTag, ok := a.resolveAssetTag(ctx, channelID, args[0])
+		if !ok {
+			return
+		}
+		filter.AssetTag = assetTag
+		title = fmt.Sprintf("Upcoming Reservations of %s", assetTag)
+	} else {
+		userEmail, ok := a.lookupUserIdentity(channelID, userID)
+		if !ok {
+			return
+		}
+		filter.ReservedBy = userEmail
+	}
+
+	reservations, err := a.DB.ListReservations(ctx, filter)
+	if err != nil {
+		log.Printf("DB Error (Reservations): %v", err)
+		a.replyStoreError(ctx, channelID, "load reservations", err)
+		return
+	}
+	if len(reservations) == 0 {
+		a.sendText(channelID, fmt.Sprintf("No reservations found for: *%s*", title))
+		return
+	}
+
+	lines := make([]string, 0, len(reservations))
+	for _, r := range reservations {
+		lines = append(lines, fmt.Sprintf("• `%s` — `%s` %s, by %s", r.ReservationID, r.AssetTag, reservationSpan(r), r.ReservedBy))
+	}
+	a.sendText(channelID, fmt.Sprintf("📌 *%s*\n\n%s", title, strings.Join(lines, "\n")))
+}
+
+// handleCancelReservation cancels one of the user's upcoming reservations. Admins may
+// cancel anyone's.
+func (a *App) handleCancelReservation(ctx context.Context, channelID, userID string, args []string) {
+	if len(args) != 1 {
+		a.sendText(channelID, "Usage: `@bot reservations cancel <ReservationID>`")
+		return
+	}
+
+	userEmail, ok := a.lookupUserIdentity(channelID, userID)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	filter := store.ReservationFilter{From: &now}
+	if !a.isAdmin(userID) {
+		filter.ReservedBy = userEmail
+	}
+	reservations, err := a.DB.ListReservations(ctx, filter)
+	if err != nil {
+		log.Printf("DB Error (Reservations): %v", err)
+		a.replyStoreError(ctx, channelID, "load reservations", err)
+		return
+	}
+
+	var found *model.Reservation
+	for i := range reservations {
+		if strings.EqualFold(reservations[i].ReservationID, args[0]) {
+			found = &reservations[i]
+		}
+	}
+	if found == nil {
+		a.sendText(channelID, fmt.Sprintf("❌ You have no upcoming reservation `%s`. See yours with `@bot reservations`.", args[0]))
+		return
+	}
+
+	ctx = store.WithActor(ctx, userEmail)
+	if err := a.DB.CancelReservation(ctx, found.AssetTag, found.ReservationID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("DB Update Error (Cancel reservation %s by %s): %v", found.ReservationID, userEmail, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("cancel reservation `%s`", found.ReservationID), err)
+		return
+	}
+
+	a.sendText(channelID, fmt.Sprintf("🗑️ Reservation `%s` of `%s` %s cancelled.", found.ReservationID, found.AssetTag, reservationSpan(*found)))
+}
+
+// reservedNow returns the reservations active right now, by asset tag. Backends that
+// don't store reservations have none.
+func (a *App) reservedNow(ctx context.Context) (map[string]model.Reservation, error) {
+	now := time.Now()
+	until := now.Add(time.Nanosecond)
+	reservations, err := a.DB.ListReservations(ctx, store.ReservationFilter{From: &now, To: &until})
+	if errors.Is(err, store.ErrUnsupported) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[string]model.Reservation, len(reservations))
+	for _, r := range reservations {
+		active[r.AssetTag] = r
+	}
+	return active, nil
+}
+
+// reservedDevices returns the devices reserved right now, replying in the channel on failure.
+func (a *App) reservedDevices(ctx context.Context, channelID string) ([]model.Device, bool) {
+	reserved, err := a.reservedNow(ctx)
+	if err != nil {
+		log.Printf("DB Error (Reservations): %v", err)
+		a.replyStoreError(ctx, channelID, "retrieve devices", err)
+		return nil, false
+	}
+
+	tags := make([]string, 0, len(reserved))
+	for tag := range reserved {
+		tags = append(tags, tag)
+	}
+	devices, err := a.DB.BatchGetDevices(ctx, tags)
+	if err != nil {
+		log.Printf("DB Error (BatchGetDevices): %v", err)
+		a.replyStoreError(ctx, channelID, "retrieve devices", err)
+		return nil, false
+	}
+	return devices, true
+}
+
+// reservationSpan describes the days a reservation covers, e.g. "from Tue, Oct 20 to
+// Thu, Oct 22", or "on Tue, Oct 20" for a single day.
+func reservationSpan(r model.Reservation) string {
+	first := r.Start.In(time.Local)
+	last := r.End.Add(-time.Nanosecond).In(time.Local)
+	if first.Format(time.DateOnly) == last.Format(time.DateOnly) {
+		return "on " + first.Format(reservationDayLayout)
+	}
+	return fmt.Sprintf("from %s to %s", first.Format(reservationDayLayout), last.Format(reservationDayLayout))
+}
+
+// reservationConflictMessage explains why a device cannot be reserved.
+func reservationConflictMessage(conflict *store.ReservationConflictError) string {
+	if conflict.Reservation != nil {
+		return fmt.Sprintf("📌 Device `%s` is already reserved by *%s* %s.",
+			conflict.AssetTag, conflict.Reservation.ReservedBy, reservationSpan(*conflict.Reservation))
+	}
+	msg := fmt.Sprintf("❌ Device `%s` is checked out to *%s* until after your reservation would start.", conflict.AssetTag, conflict.AssignedTo)
+	if conflict.DueDate != nil {
+		msg += fmt.Sprintf("\n📅 *Due back:* %s", conflict.DueDate.Format("Jan 02, 2006"))
+	}
+	return msg
+}
+
+// checkoutDueDate checks a device's reservations before assignee checks it out from now
+// until due. It returns due, cut short at the start of the next reservation by someone
+// else, or replies in the channel and returns false if someone else has it reserved now.
+func (a *App) checkoutDueDate(ctx context.Context, channelID, assetTag, assignee string, now, due time.Time) (time.Time, *model.Reservation, bool) {
+	reservations, err := a.DB.ListReservations(ctx, store.ReservationFilter{AssetTag: assetTag, From: &now, To: &due})
+	if errors.Is(err, store.ErrUnsupported) {
+		return due, nil, true
+	}
+	if err != nil {
+		log.Printf("DB Error (Reservations of %s): %v", assetTag, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("checkout device `%s`", assetTag), err)
+		return time.Time{}, nil, false
+	}
+
+	// Reservations are sorted by start, so the first one by someone else is the next.
+	for _, r := range reservations {
+		if strings.EqualFold(strings.TrimSpace(r.ReservedBy), strings.TrimSpace(assignee)) {
+			continue
+		}
+		if r.ActiveAt(now) {
+			a.sendText(channelID, reservationConflictMessage(&store.ReservationConflictError{AssetTag: assetTag, Reservation: &r}))
+			return time.Time{}, nil, false
+		}
+		return r.Start, &r, true
+	}
+	return due, nil, true
+}
@@ -2,9 +2,11 @@ package app
 
 import (
 	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/slack-go/slack"
@@ -12,6 +14,7 @@ import (
 
 var RunOverdueCheckerEvery = 1 * time.Hour // testing run check ever minute
 var ItemIsOverduePeriod = 30 * time.Hour   // testing 30 seconds until overdue
+var RunWarrantyDigestEvery = 24 * time.Hour
 
 // StartOverdueChecker runs a background loop that checks for overdue devices every 24 hours.
 func (a *App) StartOverdueChecker(ctx context.Context) {
@@ -31,16 +34,20 @@ func (a *App) StartOverdueChecker(ctx context.Context) {
 }
 
 func (a *App) checkAndNotifyOverdue(ctx context.Context) {
-	devices, _ := a.DB.ListDevices(ctx)
 	now := time.Now()
 
-	for _, dev := range devices {
-		if dev.DueDate != nil && dev.AssignedTo != "" {
-			if now.After(*dev.DueDate) {
-				log.Printf("⚠️ Device %s is past due date (%v)", dev.AssetTag, dev.DueDate)
-				a.notifyOverdueAssignee(dev)
-			}
-		}
+	overdue, err := a.DB.QueryDevices(ctx, store.DeviceFilter{
+		Availability: store.OnlyCheckedOut,
+		DueBefore:    &now,
+	})
+	if err != nil {
+		log.Printf("DB Error (overdue check): %v", err)
+		return
+	}
+
+	for _, dev := range overdue {
+		log.Printf("⚠️ Device %s is past due date (%v)", dev.AssetTag, dev.DueDate)
+		a.notifyOverdueAssignee(dev)
 	}
 }
 
@@ -66,3 +73,58 @@ func (a *App) notifyOverdueAssignee(dev model.Device) {
 
 	a.sendText(channel.ID, message)
 }
+
+// StartWarrantyDigest runs a background loop that posts the warranties expiring within
+// WarrantyWindow to WarrantyChannel once a day. It returns at once if no channel is set.
+func (a *App) StartWarrantyDigest(ctx context.Context) {
+	if a.WarrantyChannel == "" {
+		return
+	}
+
+	ticker := time.NewTicker(RunWarrantyDigestEvery)
+	defer ticker.Stop()
+
+	log.Println("🚀 Background warranty digest started...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.postWarrantyDigest(ctx)
+		}
+	}
+}
+
+func (a *App) postWarrantyDigest(ctx context.Context) {
+	now := time.Now()
+	until := now.Add(a.WarrantyWindow)
+
+	devices, err := a.DB.QueryDevices(ctx, store.DeviceFilter{WarrantyBefore: &until})
+	if err != nil {
+		log.Printf("DB Error (warranty digest): %v", err)
+		return
+	}
+
+	var expiring []model.Device
+	for _, dev := range devices {
+		// Warranties that have already run out were in earlier digests.
+		if !dev.WarrantyExpiration.Before(now) {
+			expiring = append(expiring, dev)
+		}
+	}
+	if len(expiring) == 0 {
+		log.Printf("No warranties expire in the next %d days", warrantyWindowDays(a.WarrantyWindow))
+		return
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].WarrantyExpiration.Before(*expiring[j].WarrantyExpiration)
+	})
+	a.sendText(a.WarrantyChannel, createWarrantyDigestMessage(expiring, now, a.WarrantyWindow))
+}
+
+// warrantyWindowDays rounds the digest window to whole days for display.
+func warrantyWindowDays(window time.Duration) int {
+	return int(window.Round(24*time.Hour) / (24 * time.Hour))
+}
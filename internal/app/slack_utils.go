@@ -4,7 +4,11 @@ import (
 	"bdemetris/curator/pkg/model"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/slack-go/slack"
 )
@@ -41,10 +45,24 @@ func createHelpMessage(userID string) []slack.Block {
 		"*Available Commands:*\n\n" +
 		"• `show all` - List every device in the inventory.\n" +
 		"• `show mine` - List all devices currently assigned to *you*.\n" +
-		"• `show available [filter]` - Find unassigned devices (e.g., `show available macbook`).\n" +
+		"• `show available [filter]` - Find unassigned devices not reserved right now (e.g., `show available macbook`).\n" +
+		"• `show available <type> <attribute>=<value>` - Filter by a custom attribute (e.g., `show available phone carrier=verizon`).\n" +
 		"• `show <AssetTag>` - Look up a specific device by its asset tag.\n" +
 		"• `show types` - See all categories (e.g., Laptop, Phone, Tablet).\n" +
 		"• `checkout <AssetTag>` - Assign a device to *yourself* using your Slack email.\n" +
+		"• `return <AssetTag>` - Return a device you have checked out.\n" +
+		"• `history <AssetTag>` - See who has had a device and what changed.\n" +
+		"• `reserve <AssetTag> <YYYY-MM-DD> [YYYY-MM-DD]` - Book a device for a day or a range of days.\n" +
+		"• `reservations [AssetTag]` - List your upcoming reservations, or a device's.\n" +
+		"• `reservations cancel <ReservationID>` - Cancel one of your reservations.\n" +
+		"• `waitlist <type>` - Get in line for a kind of device when none is available (e.g., `waitlist iphone`).\n" +
+		"• `waitlist [leave <type>]` - See your place in line, or leave a waitlist.\n" +
+		"• `show <AssetTag | all> at <YYYY-MM-DD>` - See a device or the inventory as it was on a date.\n" +
+		"• `show <retired | in-repair | lost>` - List devices with that status.\n" +
+		"• `show reserved` - List devices reserved right now.\n" +
+		"• `status <AssetTag> <available | in-repair | lost | retired>` - _Admins:_ Change a device's status.\n" +
+		"• `retire <AssetTag>` - _Admins:_ Retire a device, keeping its history.\n" +
+		"• `delete <AssetTag> confirm` - _Admins:_ Permanently remove a device.\n" +
 		"• `help` - Display this menu."
 
 	sectionBlock := slack.NewSectionBlock(
@@ -88,7 +106,7 @@ func (a *App) renderDeviceTable(channelID, title string, devices []model.Device)
 			break
 		}
 
-		status := "Available"
+		status := titleCase(dev.CurrentStatus().String())
 		if dev.AssignedTo != "" {
 			// Truncate email if it's too long to keep the table aligned
 			status = dev.AssignedTo
@@ -126,8 +144,15 @@ func (a *App) renderDeviceTable(channelID, title string, devices []model.Device)
 
 func (a *App) renderSingleDeviceDetail(channelID string, dev model.Device) {
 	status := "✅ Available"
-	if dev.AssignedTo != "" {
+	switch dev.CurrentStatus() {
+	case model.StatusCheckedOut:
 		status = fmt.Sprintf("👤 Assigned to %s", dev.AssignedTo)
+	case model.StatusInRepair:
+		status = "🔧 In repair"
+	case model.StatusLost:
+		status = "❓ Lost"
+	case model.StatusRetired:
+		status = "🗄️ Retired"
 	}
 
 	fields := []*slack.TextBlockObject{
@@ -147,10 +172,169 @@ func (a *App) renderSingleDeviceDetail(channelID string, dev model.Device) {
 			fmt.Sprintf("*Due Date:*\n%s", dev.DueDate.Format("Jan 02, 2006")), false, false))
 	}
 
+	// Slack allows at most 10 fields per section, so the procurement details share three.
+	if dev.SerialNumber != "" {
+		fields = append(fields, slack.NewTextBlockObject("mrkdwn",
+			fmt.Sprintf("*Serial Number:*\n%s", dev.SerialNumber), false, false))
+	}
+
+	if purchase := purchaseSummary(dev); purchase != "" {
+		fields = append(fields, slack.NewTextBlockObject("mrkdwn",
+			fmt.Sprintf("*Purchased:*\n%s", purchase), false, false))
+	}
+
+	if dev.WarrantyExpiration != nil {
+		fields = append(fields, slack.NewTextBlockObject("mrkdwn",
+			fmt.Sprintf("*Warranty Until:*\n%s", dev.WarrantyExpiration.Format("Jan 02, 2006")), false, false))
+	}
+
 	blocks := []slack.Block{
 		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text", "📱 Device Information", false, false)),
 		slack.NewSectionBlock(nil, fields, nil),
 	}
 
+	// Custom attributes get a section of their own, so they never crowd out the fixed fields.
+	if attrs := a.attributeFields(dev); len(attrs) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(nil, attrs, nil))
+	}
+
 	a.sendBlocks(channelID, blocks)
 }
+
+// attributeFields returns a field per custom attribute of the device, in the order its
+// type's schema defines them, followed by any attributes the schema does not know.
+func (a *App) attributeFields(dev model.Device) []*slack.TextBlockObject {
+	var schema model.DeviceTypeSchema
+	if a.Schemas != nil {
+		schema, _ = a.Schemas.Schema(dev.DeviceType)
+	}
+
+	var fields []*slack.TextBlockObject
+	shown := make(map[string]bool, len(dev.Attributes))
+	for _, def := range schema.Attributes {
+		if value, ok := dev.Attributes[def.Key]; ok {
+			fields = append(fields, slack.NewTextBlockObject("mrkdwn",
+				fmt.Sprintf("*%s:*\n%s", def.DisplayName(), def.Format(value)), false, false))
+			shown[def.Key] = true
+		}
+	}
+
+	var rest []string
+	for key := range dev.Attributes {
+		if !shown[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		fields = append(fields, slack.NewTextBlockObject("mrkdwn",
+			fmt.Sprintf("*%s:*\n%s", key, dev.Attributes[key]), false, false))
+	}
+
+	// Slack allows at most 10 fields per section.
+	if len(fields) > 10 {
+		fields = fields[:10]
+	}
+	return fields
+}
+
+// purchaseSummary describes when, where from and for how much a device was bought,
+// leaving out whatever is unknown.
+func purchaseSummary(dev model.Device) string {
+	var parts []string
+	if dev.PurchaseDate != nil {
+		parts = append(parts, dev.PurchaseDate.Format("Jan 02, 2006"))
+	}
+	if dev.Vendor != "" {
+		parts = append(parts, "from "+dev.Vendor)
+	}
+	if cost := model.FormatCost(dev.PurchaseCost); cost != "" {
+		parts = append(parts, "for "+cost)
+	}
+	return strings.Join(parts, " ")
+}
+
+// createWarrantyDigestMessage lists the devices whose warranty expires within window of
+// now, soonest first.
+func createWarrantyDigestMessage(devices []model.Device, now time.Time, window time.Duration) string {
+	var rows strings.Builder
+	for _, dev := range devices {
+		days := int(dev.WarrantyExpiration.Sub(now).Hours() / 24)
+		rows.WriteString(fmt.Sprintf("\n• `%s` %s %s", dev.AssetTag, dev.DeviceMake, dev.DeviceModel))
+		if dev.SerialNumber != "" {
+			rows.WriteString(fmt.Sprintf(" (S/N %s)", dev.SerialNumber))
+		}
+		rows.WriteString(fmt.Sprintf(" - expires *%s* (%d days)", dev.WarrantyExpiration.Format("Jan 02, 2006"), days))
+		if dev.Vendor != "" {
+			rows.WriteString(", bought from " + dev.Vendor)
+		}
+	}
+
+	return fmt.Sprintf("🛡️ *Warranties expiring in the next %d days* (%d devices)\n%s",
+		warrantyWindowDays(window), len(devices), rows.String())
+}
+
+func (a *App) renderDeviceHistory(channelID, assetTag string, events []model.DeviceEvent) {
+	// Keep the message well under Slack's 3000 char block limit by showing the latest events only.
+	const maxDisplay = 10
+
+	shown := events
+	if len(shown) > maxDisplay {
+		shown = shown[len(shown)-maxDisplay:]
+	}
+
+	var rows strings.Builder
+	for _, e := range shown {
+		rows.WriteString(fmt.Sprintf("• *%s* %s by %s", e.Timestamp.Format("Jan 02, 2006 15:04"), e.Type, e.Actor))
+
+		var changes []string
+		for _, c := range e.Changes() {
+			// Creations only list the fields that were set, deletions list nothing.
+			if (e.Before == nil && c.After == "") || e.After == nil {
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", c.Field, historyValue(c.Before), historyValue(c.After)))
+		}
+		if len(changes) > 0 {
+			rows.WriteString("\n    _" + strings.Join(changes, ", ") + "_")
+		}
+		rows.WriteString("\n")
+	}
+
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("🕘 *History of %s* (%d events)", assetTag, len(events)), false, false), nil, nil),
+		slack.NewDividerBlock(),
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", rows.String(), false, false), nil, nil),
+	}
+
+	if len(events) > maxDisplay {
+		footerText := fmt.Sprintf("_Showing the latest %d of %d events._", maxDisplay, len(events))
+		blocks = append(blocks, slack.NewContextBlock("",
+			slack.NewTextBlockObject("mrkdwn", footerText, false, false),
+		))
+	}
+
+	a.sendBlocks(channelID, blocks)
+}
+
+// historyValue formats a field value from a history event, marking empty values.
+func historyValue(value string) string {
+	if value == "" {
+		return "–"
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Format("Jan 02, 2006")
+	}
+	return value
+}
+
+// titleCase capitalizes the first letter of each space-separated word, e.g. "in repair"
+// as "In Repair".
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToTitle(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,347 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+
+	"github.com/slack-go/slack"
+)
+
+var RunWaitlistCheckEvery = 5 * time.Minute
+
+// holdLayout is how the end of a waitlist hold is shown, e.g. "Tue, Oct 20 at 14:00".
+const holdLayout = "Mon, Jan 02 at 15:04"
+
+// handleWaitlist joins the waitlist for a kind of device, e.g. "waitlist iphone". With no
+// arguments it lists the user's entries, and "waitlist leave <query>" leaves one.
+func (a *App) handleWaitlist(ctx context.Context, channelID, userID string, args []string) {
+	userEmail, ok := a.lookupUserIdentity(channelID, userID)
+	if !ok {
+		return
+	}
+
+	if len(args) == 0 {
+		a.showWaitlist(ctx, channelID, userEmail)
+		return
+	}
+	if args[0] == "leave" {
+		a.leaveWaitlist(ctx, channelID, userEmail, strings.Join(args[1:], " "))
+		return
+	}
+
+	query := strings.Join(args, " ")
+
+	available, ok := a.queryDevices(ctx, channelID, &store.DeviceFilter{Availability: store.OnlyAvailable})
+	if !ok {
+		return
+	}
+	reserved, err := a.reservedNow(ctx)
+	if err != nil {
+		log.Printf("DB Error (Reservations): %v", err)
+		a.replyStoreError(ctx, channelID, "retrieve devices", err)
+		return
+	}
+	for _, d := range available {
+		if _, ok := reserved[d.AssetTag]; !ok && deviceMatchesText(d, query) {
+			a.sendText(channelID, fmt.Sprintf("ℹ️ There are devices matching '%s' available right now. See them with `@bot show available %s`.", query, query))
+			return
+		}
+	}
+
+	queue, err := a.DB.ListWaitlist(ctx, store.WaitlistFilter{Query: query})
+	if err != nil {
+		log.Printf("DB Error (Waitlist): %v", err)
+		a.replyStoreError(ctx, channelID, "load the waitlist", err)
+		return
+	}
+	for i, e := range queue {
+		if strings.EqualFold(e.Requester, userEmail) {
+			a.sendText(channelID, fmt.Sprintf("ℹ️ You're already on the waitlist for '%s', number %d in line.", query, i+1))
+			return
+		}
+	}
+
+	entry := store.NewWaitlistEntry(query, userEmail, userID)
+	if err := a.DB.JoinWaitlist(ctx, entry); err != nil {
+		log.Printf("DB Update Error (Waitlist %s by %s): %v", query, userEmail, err)
+		a.replyStoreError(ctx, channelID, fmt.Sprintf("join the waitlist for '%s'", query), err)
+		return
+	}
+
+	a.sendText(channelID, fmt.Sprintf("⏳ You're number %d in line for '%s'. I'll message you when one comes back and hold it for you for %s.",
+		len(queue)+1, query, holdDuration(a.WaitlistHold)))
+}
+
+// showWaitlist lists the user's waitlist entries with their place in line.
+func (a *App) showWaitlist(ctx context.Context, channelID, userEmail string) {
+	entries, err := a.DB.ListWaitlist(ctx, store.WaitlistFilter{})
+	if err != nil {
+		log.Printf("DB Error (Waitlist): %v", err)
+		a.replyStoreError(ctx, channelID, "load the waitlist", err)
+		return
+	}
+
+	var lines []string
+	ahead := make(map[string]int)
+	for _, e := range entries {
+		query := strings.ToLower(e.Query)
+		ahead[query]++
+		if !strings.EqualFold(e.Requester, userEmail) {
+			continue
+		}
+		if e.Offer != nil {
+			lines = append(lines, fmt.Sprintf("• '%s' — `%s` is held for you until %s", e.Query, e.Offer.AssetTag, e.Offer.ExpiresAt.In(time.Local).Format(holdLayout)))
+		} else {
+			lines = append(lines, fmt.Sprintf("• '%s' — number %d in line", e.Query, ahead[query]))
+		}
+	}
+	if len(lines) == 0 {
+		a.sendText(channelID, "You're not on any waitlist. Join one with `@bot waitlist <type>`.")
+		return
+	}
+	a.sendText(channelID, fmt.Sprintf("⏳ *Your Waitlists*\n\n%s", strings.Join(lines, "\n")))
+}
+
+// leaveWaitlist removes the user from the waitlist for query, passing on any device held
+// for them.
+func (a *App) leaveWaitlist(ctx context.Context, channelID, userEmail, query string) {
+	if query == "" {
+		a.sendText(channelID, "Usage: `@bot waitlist leave <type>`")
+		return
+	}
+
+	entries, err := a.DB.ListWaitlist(ctx, store.WaitlistFilter{Query: query, Requester: userEmail})
+	if err != nil {
+		log.Printf("DB Error (Waitlist): %v", err)
+		a.replyStoreError(ctx, channelID, "load the waitlist", err)
+		return
+	}
+	if len(entries) == 0 {
+		a.sendText(channelID, fmt.Sprintf("ℹ️ You're not on the waitlist for '%s'.", query))
+		return
+	}
+
+	for _, e := range entries {
+		if err := a.removeWaitlistEntry(ctx, e); err != nil {
+			log.Printf("DB Update Error (Leave waitlist %s by %s): %v", query, userEmail, err)
+			a.replyStoreError(ctx, channelID, fmt.Sprintf("leave the waitlist for '%s'", query), err)
+			return
+		}
+	}
+	a.sendText(channelID, fmt.Sprintf("👋 You've left the waitlist for '%s'.", query))
+}
+
+// removeWaitlistEntry takes an entry off the waitlist. A device held for it is released
+// and offered to the next person in line.
+func (a *App) removeWaitlistEntry(ctx context.Context, e model.WaitlistEntry) error {
+	if err := a.DB.LeaveWaitlist(ctx, e.EntryID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	if e.Offer == nil {
+		return nil
+	}
+
+	if err := a.DB.CancelReservation(ctx, e.Offer.AssetTag, e.Offer.ReservationID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("DB Update Error (Release hold of %s for %s): %v", e.Offer.AssetTag, e.Requester, err)
+	}
+	a.offerDevice(ctx, e.Offer.AssetTag)
+	return nil
+}
+
+// offerDevice holds an available device for the first person in line whose waitlist it
+// matches, and messages them. The hold is a reservation from now for WaitlistHold, so the
+// device stays out of "show available" and nobody else can check it out meanwhile.
+func (a *App) offerDevice(ctx context.Context, assetTag string) {
+	device, err := a.DB.GetDevice(ctx, assetTag)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("DB Error (Waitlist offer of %s): %v", assetTag, err)
+		}
+		return
+	}
+	if device.CurrentStatus() != model.StatusAvailable {
+		return
+	}
+
+	queue, err := a.waiting(ctx, store.WaitlistFilter{})
+	if err != nil {
+		log.Printf("DB Error (Waitlist offer of %s): %v", assetTag, err)
+		return
+	}
+
+	for _, e := range queue {
+		if e.Offer != nil || !deviceMatchesText(device, e.Query) {
+			continue
+		}
+
+		now := time.Now()
+		hold := store.NewReservation(device.AssetTag, e.Requester, now, now.Add(a.WaitlistHold))
+		if err := a.DB.CreateReservation(ctx, hold); err != nil {
+			// Most likely reserved by someone else already, which waitlists don't jump.
+			if !errors.Is(err, store.ErrConflict) {
+				log.Printf("DB Update Error (Hold %s for %s): %v", device.AssetTag, e.Requester, err)
+			}
+			return
+		}
+
+		offer := model.WaitlistOffer{
+			AssetTag:      device.AssetTag,
+			ReservationID: hold.ReservationID,
+			OfferedAt:     hold.Start,
+			ExpiresAt:     hold.End,
+		}
+		if err := a.DB.OfferWaitlistEntry(ctx, e.EntryID, offer); err != nil {
+			if err := a.DB.CancelReservation(ctx, device.AssetTag, hold.ReservationID); err != nil {
+				log.Printf("DB Update Error (Release hold of %s for %s): %v", device.AssetTag, e.Requester, err)
+			}
+			// Someone who left the line or was just offered another device is skipped.
+			if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrConflict) {
+				continue
+			}
+			log.Printf("DB Update Error (Waitlist offer of %s to %s): %v", device.AssetTag, e.Requester, err)
+			return
+		}
+
+		log.Printf("Holding device %s for %s from the waitlist for '%s'", device.AssetTag, e.Requester, e.Query)
+		a.sendDirectMessage(e.SlackUserID, fmt.Sprintf(
+			"🎉 Good news! Device `%s` (%s) matching your waitlist for '%s' is back. It's held for you until %s; check it out with `@bot checkout %s`.",
+			device.AssetTag, device.DeviceModel, e.Query, hold.End.In(time.Local).Format(holdLayout), device.AssetTag))
+		return
+	}
+}
+
+// settleWaitlist takes the user off every waitlist the device they just checked out
+// satisfies, releasing devices held for them elsewhere.
+func (a *App) settleWaitlist(ctx context.Context, userEmail string, device model.Device) {
+	entries, err := a.waiting(ctx, store.WaitlistFilter{Requester: userEmail})
+	if err != nil {
+		log.Printf("DB Error (Waitlist of %s): %v", userEmail, err)
+		return
+	}
+
+	for _, e := range entries {
+		heldHere := e.Offer != nil && e.Offer.AssetTag == device.AssetTag
+		if !heldHere && !deviceMatchesText(device, e.Query) {
+			continue
+		}
+		if heldHere {
+			// The device is checked out now, so its hold is cancelled rather than passed on.
+			if err := a.DB.CancelReservation(ctx, device.AssetTag, e.Offer.ReservationID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				log.Printf("DB Update Error (Release hold of %s for %s): %v", device.AssetTag, userEmail, err)
+			}
+			e.Offer = nil
+		}
+		if err := a.removeWaitlistEntry(ctx, e); err != nil {
+			log.Printf("DB Update Error (Leave waitlist %s by %s): %v", e.Query, userEmail, err)
+		}
+	}
+}
+
+// StartWaitlistChecker runs a background loop that expires waitlist holds nobody picked
+// up and offers available devices to whoever is waiting for them. It checks once at
+// startup too, to catch devices that came back while the bot was down.
+func (a *App) StartWaitlistChecker(ctx context.Context) {
+	ticker := time.NewTicker(RunWaitlistCheckEvery)
+	defer ticker.Stop()
+
+	log.Println("🚀 Background waitlist checker started...")
+
+	a.processWaitlist(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.processWaitlist(ctx)
+		}
+	}
+}
+
+func (a *App) processWaitlist(ctx context.Context) {
+	entries, err := a.waiting(ctx, store.WaitlistFilter{})
+	if err != nil {
+		log.Printf("DB Error (waitlist check): %v", err)
+		return
+	}
+
+	now := time.Now()
+	var waiting []string
+	for _, e := range entries {
+		if e.Offer == nil {
+			waiting = append(waiting, e.Query)
+			continue
+		}
+		if e.Offer.ExpiresAt.After(now) {
+			continue
+		}
+
+		log.Printf("⚠️ Hold of %s for %s expired", e.Offer.AssetTag, e.Requester)
+		if err := a.removeWaitlistEntry(ctx, e); err != nil {
+			log.Printf("DB Update Error (Expire waitlist entry %s): %v", e.EntryID, err)
+			continue
+		}
+		a.sendDirectMessage(e.SlackUserID, fmt.Sprintf(
+			"⌛ Your hold on device `%s` has expired, so it went to the next person in line. Run `@bot waitlist %s` to join again.",
+			e.Offer.AssetTag, e.Query))
+	}
+	if len(waiting) == 0 {
+		return
+	}
+
+	available, err := a.DB.QueryDevices(ctx, store.DeviceFilter{Availability: store.OnlyAvailable})
+	if err != nil {
+		log.Printf("DB Error (waitlist check): %v", err)
+		return
+	}
+	reserved, err := a.reservedNow(ctx)
+	if err != nil {
+		log.Printf("DB Error (waitlist check): %v", err)
+		return
+	}
+	for _, d := range available {
+		if _, ok := reserved[d.AssetTag]; ok {
+			continue
+		}
+		if slices.ContainsFunc(waiting, func(query string) bool { return deviceMatchesText(d, query) }) {
+			a.offerDevice(ctx, d.AssetTag)
+		}
+	}
+}
+
+// waiting lists the waitlist entries matching filter for the background checks. Backends
+// that don't store the waitlist have nobody waiting.
+func (a *App) waiting(ctx context.Context, filter store.WaitlistFilter) ([]model.WaitlistEntry, error) {
+	entries, err := a.DB.ListWaitlist(ctx, filter)
+	if errors.Is(err, store.ErrUnsupported) {
+		return nil, nil
+	}
+	return entries, err
+}
+
+// sendDirectMessage messages a Slack user privately.
+func (a *App) sendDirectMessage(userID, text string) {
+	channel, _, _, err := a.API.OpenConversation(&slack.OpenConversationParameters{
+		Users: []string{userID},
+	})
+	if err != nil {
+		log.Printf("❌ Failed to open DM with %s: %v", userID, err)
+		return
+	}
+	a.sendText(channel.ID, text)
+}
+
+// holdDuration describes how long waitlist holds last, e.g. "24 hours".
+func holdDuration(hold time.Duration) string {
+	if hours := int(hold.Round(time.Hour) / time.Hour); hours != 1 {
+		return fmt.Sprintf("%d hours", hours)
+	}
+	return "1 hour"
+}
@@ -2,11 +2,16 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -18,60 +23,254 @@ import (
 )
 
 type DynamoClient struct {
-	svc *dynamodb.Client
+	svc               *dynamodb.Client
+	table             string
+	eventsTable       string
+	reservationsTable string
+	waitlistTable     string
+
+	// activeIndexes records which secondary indexes were ACTIVE at startup.
+	// Queries fall back to a table scan while an index is still being built.
+	activeIndexes map[string]bool
 }
 
 var _ store.Store = (*DynamoClient)(nil)
 
-const tableName = "Devices"
+// Tables used when the table names in DynamoConfig are empty.
+const (
+	DefaultDynamoTable             = "Devices"
+	DefaultDynamoEventsTable       = "DeviceEvents"
+	DefaultDynamoReservationsTable = "DeviceReservations"
+	DefaultDynamoWaitlistTable     = "DeviceWaitlist"
+)
+
+// localRegion is used against a custom endpoint when no region is configured;
+// DynamoDB Local accepts any region.
+const localRegion = "us-west-2"
+
+// Global secondary indexes. Both are sparse: their keys are only written for devices
+// with an assignee or a due date, so they only hold devices that are out.
+const (
+	assigneeIndex = "AssigneeKey-index"
+	dueDateIndex  = "DueShard-DueDate-index"
+)
+
+// Index key attributes, which marshalDevice writes alongside the device's own attributes.
+// DeviceFilter.AssignedTo ignores case, so the assignee is keyed in lower case. Every due
+// device shares one DueShard partition, so that a single Query can range over DueDate.
+const (
+	assigneeKeyAttribute = "AssigneeKey"
+	dueShardAttribute    = "DueShard"
+	dueShard             = "due"
+)
+
+// dueDateLayout is how DueDate is stored: fixed-width UTC, so that due dates compare
+// correctly as strings in a KeyConditionExpression.
+const dueDateLayout = "2006-01-02T15:04:05.000000000Z"
+
+// dynamoIndexKey is the key schema of a global secondary index. rangeKey is empty for
+// indexes with a hash key only.
+type dynamoIndexKey struct {
+	hashKey, rangeKey string
+}
+
+// deviceIndexes describes each global secondary index by its key attributes.
+var deviceIndexes = map[string]dynamoIndexKey{
+	assigneeIndex: {hashKey: assigneeKeyAttribute},
+	dueDateIndex:  {hashKey: dueShardAttribute, rangeKey: "DueDate"},
+}
+
+// DynamoConfig configures the DynamoDB provider. The zero value connects to AWS
+// using the default credential chain and region, and an on-demand "Devices" table.
+type DynamoConfig struct {
+	// Endpoint overrides the service endpoint, e.g. "http://localhost:8000" for
+	// DynamoDB Local. Leave empty for AWS.
+	Endpoint string
+	// Region overrides the region from the environment or shared config.
+	Region string
+	// Profile selects a named profile from the shared config and credentials files.
+	// When Endpoint is set and Profile is empty, static dummy credentials are used.
+	Profile string
+	// Table is the device table name. Defaults to DefaultDynamoTable.
+	Table string
+	// EventsTable is the device history table name. Defaults to DefaultDynamoEventsTable.
+	EventsTable string
+	// ReservationsTable is the reservations table name. Defaults to
+	// DefaultDynamoReservationsTable.
+	ReservationsTable string
+	// WaitlistTable is the waitlist table name. Defaults to DefaultDynamoWaitlistTable.
+	WaitlistTable string
+	// BillingMode applies when the tables are created: types.BillingModePayPerRequest
+	// (the default) or types.BillingModeProvisioned.
+	BillingMode types.BillingMode
+	// ReadCapacity and WriteCapacity are the provisioned throughput of the table and
+	// each index in provisioned mode. Both default to 1.
+	ReadCapacity  int64
+	WriteCapacity int64
+}
+
+// ParseDynamoBillingMode accepts "on-demand", "provisioned", or the DynamoDB
+// names PAY_PER_REQUEST and PROVISIONED. An empty string means on-demand.
+func ParseDynamoBillingMode(s string) (types.BillingMode, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "", "ON-DEMAND", "ON_DEMAND", string(types.BillingModePayPerRequest):
+		return types.BillingModePayPerRequest, nil
+	case string(types.BillingModeProvisioned):
+		return types.BillingModeProvisioned, nil
+	}
+	return "", fmt.Errorf("unknown DynamoDB billing mode %q, expected on-demand or provisioned", s)
+}
 
-// NewDynamoClient configures and returns a client connected to DynamoDB Local.
-func NewDynamoStore(ctx context.Context, endpoint string) (store.Store, error) {
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion("us-west-2"),
-		config.WithCredentialsProvider(
+// NewDynamoStore configures and returns a client for the configured tables, creating
+// the tables and indexes that do not exist yet.
+func NewDynamoStore(ctx context.Context, dc DynamoConfig) (store.Store, error) {
+	var opts []func(*config.LoadOptions) error
+	if dc.Region != "" {
+		opts = append(opts, config.WithRegion(dc.Region))
+	}
+	if dc.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(dc.Profile))
+	} else if dc.Endpoint != "" {
+		// DynamoDB Local accepts any credentials, so don't require real ones.
+		opts = append(opts, config.WithCredentialsProvider(
 			aws.NewCredentialsCache(
 				credentials.NewStaticCredentialsProvider("dummy", "dummy", ""),
 			),
-		),
-	)
+		))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load SDK configuration: %w", err)
 	}
+	if cfg.Region == "" {
+		if dc.Endpoint == "" {
+			return nil, fmt.Errorf("no AWS region configured: set AWS_REGION or a profile with a region")
+		}
+		cfg.Region = localRegion
+	}
 
-	svc := dynamodb.NewFromConfig(cfg,
-		dynamodb.WithEndpointResolver(
-			dynamodb.EndpointResolverFromURL(endpoint),
-		),
+	svc := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		if dc.Endpoint != "" {
+			o.BaseEndpoint = aws.String(dc.Endpoint)
+		}
+	})
 
-		func(o *dynamodb.Options) {
-			o.Region = "us-west-2"
-		},
-	)
+	c := &DynamoClient{svc: svc, table: dc.Table, eventsTable: dc.EventsTable, reservationsTable: dc.ReservationsTable, waitlistTable: dc.WaitlistTable}
+	if c.table == "" {
+		c.table = DefaultDynamoTable
+	}
+	if c.eventsTable == "" {
+		c.eventsTable = DefaultDynamoEventsTable
+	}
+	if c.reservationsTable == "" {
+		c.reservationsTable = DefaultDynamoReservationsTable
+	}
+	if c.waitlistTable == "" {
+		c.waitlistTable = DefaultDynamoWaitlistTable
+	}
 
-	if err := ensureTableExists(ctx, svc); err != nil {
+	if err := c.ensureTableExists(ctx, dc, c.deviceTableDefinition(dc)); err != nil {
 		return nil, fmt.Errorf("failed to ensure table exists: %w", err)
 	}
+	if err := c.ensureTableExists(ctx, dc, c.eventsTableDefinition()); err != nil {
+		return nil, fmt.Errorf("failed to ensure events table exists: %w", err)
+	}
+	if err := c.ensureTableExists(ctx, dc, c.reservationsTableDefinition()); err != nil {
+		return nil, fmt.Errorf("failed to ensure reservations table exists: %w", err)
+	}
+	if err := c.ensureTableExists(ctx, dc, c.waitlistTableDefinition()); err != nil {
+		return nil, fmt.Errorf("failed to ensure waitlist table exists: %w", err)
+	}
+
+	c.activeIndexes, err = c.ensureIndexesExist(ctx, dc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure indexes exist: %w", err)
+	}
+	if err := c.backfillIndexKeys(ctx); err != nil {
+		log.Printf("Warning: could not add index keys to existing devices, queries may miss them: %v", err)
+	}
+
+	return c, nil
+}
+
+// provisionedThroughput returns the throughput for a new table or index, or nil in
+// on-demand mode, where DynamoDB rejects any throughput settings.
+func (dc DynamoConfig) provisionedThroughput() *types.ProvisionedThroughput {
+	if dc.billingMode() != types.BillingModeProvisioned {
+		return nil
+	}
+
+	read, write := dc.ReadCapacity, dc.WriteCapacity
+	if read <= 0 {
+		read = 1
+	}
+	if write <= 0 {
+		write = 1
+	}
+	return &types.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(read),
+		WriteCapacityUnits: aws.Int64(write),
+	}
+}
 
-	return &DynamoClient{svc: svc}, nil
+func (dc DynamoConfig) billingMode() types.BillingMode {
+	if dc.BillingMode == "" {
+		return types.BillingModePayPerRequest
+	}
+	return dc.BillingMode
 }
 
-// ensureTableExists checks for and creates the required table.
-func ensureTableExists(ctx context.Context, svc *dynamodb.Client) error {
-	_, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+// ensureTableExists creates the table described by def unless it already exists,
+// waiting until it is ACTIVE. The billing mode is taken from dc.
+func (c *DynamoClient) ensureTableExists(ctx context.Context, dc DynamoConfig, def *dynamodb.CreateTableInput) error {
+	_, err := c.svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: def.TableName})
 	if err == nil {
-		fmt.Println("DynamoDB table already exists. Skipping creation.")
+		log.Printf("DynamoDB table %s already exists. Skipping creation.", aws.ToString(def.TableName))
 		return nil
 	}
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		return fmt.Errorf("error describing table %s: %w", aws.ToString(def.TableName), err)
+	}
+
+	log.Printf("Creating DynamoDB table: %s (%s)...", aws.ToString(def.TableName), dc.billingMode())
+	def.BillingMode = dc.billingMode()
+	def.ProvisionedThroughput = dc.provisionedThroughput()
+	if _, err := c.svc.CreateTable(ctx, def); err != nil {
+		return fmt.Errorf("error creating table: %w", err)
+	}
+
+	// On AWS the table is CREATING for a while and rejects reads and writes until ACTIVE.
+	waiter := dynamodb.NewTableExistsWaiter(c.svc)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: def.TableName}, 5*time.Minute); err != nil {
+		return fmt.Errorf("table %s did not become active: %w", aws.ToString(def.TableName), err)
+	}
+	log.Println("Table created successfully.")
+	return nil
+}
 
-	log.Printf("Creating DynamoDB table: %s...", tableName)
-	_, err = svc.CreateTable(ctx, &dynamodb.CreateTableInput{
-		TableName: aws.String(tableName),
+// deviceTableDefinition describes the device table, keyed by AssetTag, with its indexes.
+func (c *DynamoClient) deviceTableDefinition(dc DynamoConfig) *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(c.table),
 		AttributeDefinitions: []types.AttributeDefinition{
 			{
 				AttributeName: aws.String("AssetTag"),
 				AttributeType: types.ScalarAttributeTypeS,
 			},
+			{
+				AttributeName: aws.String(assigneeKeyAttribute),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String(dueShardAttribute),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("DueDate"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
 		},
 		KeySchema: []types.KeySchemaElement{
 			{
@@ -79,16 +278,136 @@ func ensureTableExists(ctx context.Context, svc *dynamodb.Client) error {
 				KeyType:       types.KeyTypeHash,
 			},
 		},
-		ProvisionedThroughput: &types.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(1),
-			WriteCapacityUnits: aws.Int64(1),
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			indexDefinition(assigneeIndex, dc.provisionedThroughput()),
+			indexDefinition(dueDateIndex, dc.provisionedThroughput()),
 		},
-	})
+	}
+}
+
+// eventsTableDefinition describes the history table. Each device's events share the
+// AssetTag partition and sort by EventID, which is time-ordered.
+func (c *DynamoClient) eventsTableDefinition() *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(c.eventsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("AssetTag"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("EventID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("AssetTag"),
+				KeyType:       types.KeyTypeHash,
+			},
+			{
+				AttributeName: aws.String("EventID"),
+				KeyType:       types.KeyTypeRange,
+			},
+		},
+	}
+}
+
+// indexDefinition returns the definition of one of deviceIndexes. throughput must be
+// nil when the table uses on-demand billing.
+func indexDefinition(name string, throughput *types.ProvisionedThroughput) types.GlobalSecondaryIndex {
+	key := deviceIndexes[name]
+	keySchema := []types.KeySchemaElement{
+		{
+			AttributeName: aws.String(key.hashKey),
+			KeyType:       types.KeyTypeHash,
+		},
+	}
+	if key.rangeKey != "" {
+		keySchema = append(keySchema, types.KeySchemaElement{
+			AttributeName: aws.String(key.rangeKey),
+			KeyType:       types.KeyTypeRange,
+		})
+	}
+
+	return types.GlobalSecondaryIndex{
+		IndexName:             aws.String(name),
+		KeySchema:             keySchema,
+		Projection:            &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		ProvisionedThroughput: throughput,
+	}
+}
+
+// attributeDefinitions defines the key attributes of an index, all of them strings.
+func (key dynamoIndexKey) attributeDefinitions() []types.AttributeDefinition {
+	var defs []types.AttributeDefinition
+	for _, name := range []string{key.hashKey, key.rangeKey} {
+		if name != "" {
+			defs = append(defs, types.AttributeDefinition{
+				AttributeName: aws.String(name),
+				AttributeType: types.ScalarAttributeTypeS,
+			})
+		}
+	}
+	return defs
+}
+
+// ensureIndexesExist adds any missing secondary index to a table created before the
+// indexes existed, and returns the set of indexes that are ready to query.
+func (c *DynamoClient) ensureIndexesExist(ctx context.Context, dc DynamoConfig) (map[string]bool, error) {
+	out, err := c.svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(c.table)})
 	if err != nil {
-		return fmt.Errorf("error creating table: %w", err)
+		return nil, err
 	}
-	log.Println("Table created successfully.")
-	return nil
+
+	active := make(map[string]bool)
+	existing := make(map[string]bool)
+	for _, gsi := range out.Table.GlobalSecondaryIndexes {
+		name := aws.ToString(gsi.IndexName)
+		existing[name] = true
+		active[name] = gsi.IndexStatus == types.IndexStatusActive
+	}
+
+	// New indexes follow the existing table's billing mode, which may differ from
+	// the configured one if the table was created elsewhere.
+	// Tables created before billing modes existed report no summary and are provisioned.
+	provisioned := dc
+	provisioned.BillingMode = types.BillingModeProvisioned
+	throughput := provisioned.provisionedThroughput()
+	if summary := out.Table.BillingModeSummary; summary != nil && summary.BillingMode == types.BillingModePayPerRequest {
+		throughput = nil
+	}
+
+	for _, name := range []string{assigneeIndex, dueDateIndex} {
+		if existing[name] {
+			continue
+		}
+
+		// DynamoDB only allows one index to be created per UpdateTable call, and rejects
+		// further updates while it backfills, so the rest are added on a later start.
+		log.Printf("Creating DynamoDB index %s on %s...", name, c.table)
+		def := indexDefinition(name, throughput)
+		_, err := c.svc.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName:            aws.String(c.table),
+			AttributeDefinitions: deviceIndexes[name].attributeDefinitions(),
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+				{
+					Create: &types.CreateGlobalSecondaryIndexAction{
+						IndexName:             def.IndexName,
+						KeySchema:             def.KeySchema,
+						Projection:            def.Projection,
+						ProvisionedThroughput: def.ProvisionedThroughput,
+					},
+				},
+			},
+		})
+		if err != nil {
+			log.Printf("Warning: could not create index %s, queries will scan instead: %v", name, err)
+		}
+		break
+	}
+
+	return active, nil
 }
 
 // Close is implemented to satisfy the Store interface.
@@ -98,34 +417,32 @@ func (c *DynamoClient) Close() error {
 	return nil
 }
 
-// PutDevice stores a Device item in the table.
+// PutDevice stores a Device item in the table, replacing any existing device with the
+// same AssetTag.
 func (c *DynamoClient) PutDevice(ctx context.Context, device model.Device) error {
-	item, err := attributevalue.MarshalMap(device)
-	if err != nil {
-		return fmt.Errorf("failed to marshal item: %w", err)
+	if device.AssetTag == "" {
+		return fmt.Errorf("device is missing AssetTag")
 	}
 
-	_, err = c.svc.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(tableName),
-		Item:      item,
+	return c.writeDevice(ctx, device.AssetTag, func(before *model.Device) (*model.Device, []types.TransactWriteItem, error) {
+		return &device, nil, nil
 	})
-	return err
 }
 
 // GetDevice retrieves a Device item by its Serial Number.
 func (c *DynamoClient) GetDevice(ctx context.Context, deviceID string) (model.Device, error) {
 	result, err := c.svc.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(tableName),
+		TableName: aws.String(c.table),
 		Key: map[string]types.AttributeValue{
 			"AssetTag": &types.AttributeValueMemberS{Value: deviceID},
 		},
 	})
 	if err != nil {
-		return model.Device{}, err
+		return model.Device{}, dynamoError(fmt.Errorf("dynamodb get failed for ID %s: %w", deviceID, err))
 	}
 
 	if result.Item == nil {
-		return model.Device{}, fmt.Errorf("AssetTag %s not found", deviceID)
+		return model.Device{}, &store.NotFoundError{AssetTag: deviceID}
 	}
 
 	var device model.Device
@@ -133,76 +450,724 @@ func (c *DynamoClient) GetDevice(ctx context.Context, deviceID string) (model.De
 	if err != nil {
 		return model.Device{}, fmt.Errorf("failed to unmarshal item: %w", err)
 	}
+	tidyDevice(&device)
 
 	return device, nil
 }
 
 // ListDevices retrieves all device items from the DynamoDB table.
 func (c *DynamoClient) ListDevices(ctx context.Context) ([]model.Device, error) {
+	var devices []model.Device
+	err := c.IterateDevices(ctx, func(device model.Device) error {
+		devices = append(devices, device)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// IterateDevices scans the table one page at a time, following LastEvaluatedKey
+// until every item has been passed to fn.
+func (c *DynamoClient) IterateDevices(ctx context.Context, fn func(model.Device) error) error {
 	// A Scan without a FilterExpression retrieves all items
 	scanInput := &dynamodb.ScanInput{
-		TableName: aws.String(tableName),
+		TableName: aws.String(c.table),
 		Select:    types.SelectAllAttributes,
 	}
 
-	result, err := c.svc.Scan(ctx, scanInput)
+	return c.scanPages(ctx, scanInput, fn)
+}
+
+// UpdateDevice applies the patch to the device as read and writes it back.
+func (c *DynamoClient) UpdateDevice(ctx context.Context, deviceID string, patch store.DevicePatch) error {
+	if err := patch.Validate(); err != nil {
+		return fmt.Errorf("invalid update for device ID %s: %w", deviceID, err)
+	}
+
+	return c.writeDevice(ctx, deviceID, func(before *model.Device) (*model.Device, []types.TransactWriteItem, error) {
+		if before == nil {
+			return nil, nil, &store.NotFoundError{AssetTag: deviceID}
+		}
+		after := patch.Apply(*before)
+		return &after, nil, nil
+	})
+}
+
+// CheckoutDevice assigns the device if its status allows a checkout, it is not held by
+// someone else, and no reservation by someone else overlaps the checkout. A reservation
+// made meanwhile bumps the device's revision, so the transaction then fails and the
+// checkout is checked again.
+func (c *DynamoClient) CheckoutDevice(ctx context.Context, deviceID, assignee string, assignedAt, dueAt time.Time) error {
+	return c.writeDevice(ctx, deviceID, func(before *model.Device) (*model.Device, []types.TransactWriteItem, error) {
+		if before == nil {
+			return nil, nil, &store.NotFoundError{AssetTag: deviceID}
+		}
+		if err := store.CheckTransition(*before, model.StatusCheckedOut); err != nil {
+			return nil, nil, err
+		}
+		if before.AssignedTo != "" && before.AssignedTo != assignee {
+			return nil, nil, &store.ConflictError{AssetTag: deviceID, AssignedTo: before.AssignedTo, DueDate: before.DueDate}
+		}
+		reservations, err := c.queryReservations(ctx, deviceID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := store.CheckCheckout(deviceID, assignee, reservations, assignedAt, dueAt); err != nil {
+			return nil, nil, err
+		}
+
+		after := *before
+		after.Status, after.AssignedTo, after.AssignedDate, after.DueDate = model.StatusCheckedOut, assignee, &assignedAt, &dueAt
+		return &after, nil, nil
+	})
+}
+
+// ReturnDevice clears the assignment if the device is checked out to assignee.
+func (c *DynamoClient) ReturnDevice(ctx context.Context, deviceID, assignee string) error {
+	return c.writeDevice(ctx, deviceID, func(before *model.Device) (*model.Device, []types.TransactWriteItem, error) {
+		if before == nil {
+			return nil, nil, &store.NotFoundError{AssetTag: deviceID}
+		}
+		if before.AssignedTo != assignee {
+			return nil, nil, &store.ConflictError{AssetTag: deviceID, AssignedTo: before.AssignedTo, DueDate: before.DueDate}
+		}
+
+		after := *before
+		after.Status, after.AssignedTo, after.AssignedDate, after.DueDate = model.StatusAvailable, "", nil, nil
+		return &after, nil, nil
+	})
+}
+
+// DeleteDevice deletes the device item. Its events stay in the events table, followed by
+// a delete event written in the same transaction.
+func (c *DynamoClient) DeleteDevice(ctx context.Context, deviceID string) error {
+	err := c.writeDevice(ctx, deviceID, func(before *model.Device) (*model.Device, []types.TransactWriteItem, error) {
+		if before == nil {
+			return nil, nil, &store.NotFoundError{AssetTag: deviceID}
+		}
+		return nil, nil, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("dynamodb scan failed: %w", err)
+		return err
 	}
+	c.deleteReservations(ctx, deviceID)
+	return nil
+}
 
+// SetDeviceStatus moves the device to status if its current status allows it, and
+// removes any assignment if status ends a checkout.
+func (c *DynamoClient) SetDeviceStatus(ctx context.Context, deviceID string, status model.DeviceStatus) error {
+	if err := store.ValidateStatus(status); err != nil {
+		return fmt.Errorf("invalid status for device ID %s: %w", deviceID, err)
+	}
+
+	return c.writeDevice(ctx, deviceID, func(before *model.Device) (*model.Device, []types.TransactWriteItem, error) {
+		if before == nil {
+			return nil, nil, &store.NotFoundError{AssetTag: deviceID}
+		}
+		if err := store.CheckTransition(*before, status); err != nil {
+			return nil, nil, err
+		}
+
+		after := *before
+		after.Status = status
+		if status.ClearsAssignment() {
+			after.AssignedTo, after.AssignedDate, after.DueDate = "", nil, nil
+		}
+		return &after, nil, nil
+	})
+}
+
+// QueryDevices returns the devices matching filter. An assignee is queried by key in the
+// assignee index, and due dates by range in the due-date index. Checked-out devices are
+// read by scanning the sparse assignee index, which only holds devices with an assignee;
+// anything else scans the table. filter.Matches checks the rest of the filter.
+func (c *DynamoClient) QueryDevices(ctx context.Context, filter store.DeviceFilter) ([]model.Device, error) {
 	var devices []model.Device
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &devices)
+	collect := func(device model.Device) error {
+		if filter.Matches(device) {
+			devices = append(devices, device)
+		}
+		return nil
+	}
+
+	var err error
+	switch {
+	case filter.AssignedTo != "" && c.activeIndexes[assigneeIndex]:
+		err = c.queryPages(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(c.table),
+			IndexName:              aws.String(assigneeIndex),
+			KeyConditionExpression: aws.String("#key = :assignee"),
+			ExpressionAttributeNames: map[string]string{
+				"#key": assigneeKeyAttribute,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":assignee": &types.AttributeValueMemberS{Value: assigneeKey(filter.AssignedTo)},
+			},
+		}, collect)
+
+	case filter.DueBefore != nil && c.activeIndexes[dueDateIndex]:
+		err = c.queryPages(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(c.table),
+			IndexName:              aws.String(dueDateIndex),
+			KeyConditionExpression: aws.String("#shard = :shard AND #due < :before"),
+			ExpressionAttributeNames: map[string]string{
+				"#shard": dueShardAttribute,
+				"#due":   "DueDate",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":shard":  &types.AttributeValueMemberS{Value: dueShard},
+				":before": &types.AttributeValueMemberS{Value: filter.DueBefore.UTC().Format(dueDateLayout)},
+			},
+		}, collect)
+
+	case filter.Availability == store.OnlyCheckedOut && c.activeIndexes[assigneeIndex]:
+		err = c.scanPages(ctx, &dynamodb.ScanInput{
+			TableName: aws.String(c.table),
+			IndexName: aws.String(assigneeIndex),
+		}, collect)
+
+	default:
+		err = c.IterateDevices(ctx, collect)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal devices: %w", err)
+		return nil, err
 	}
 
 	return devices, nil
 }
 
-func (c *DynamoClient) UpdateDevice(ctx context.Context, deviceID string, updates map[string]interface{}) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no update parameters provided for device ID %s", deviceID)
+// scanPages runs a paginated Scan, passing every item to fn.
+func (c *DynamoClient) scanPages(ctx context.Context, input *dynamodb.ScanInput, fn func(model.Device) error) error {
+	paginator := dynamodb.NewScanPaginator(c.svc, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return dynamoError(fmt.Errorf("dynamodb scan failed: %w", err))
+		}
+		if err := eachDevice(page.Items, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryPages runs a paginated Query, passing every item to fn.
+func (c *DynamoClient) queryPages(ctx context.Context, input *dynamodb.QueryInput, fn func(model.Device) error) error {
+	paginator := dynamodb.NewQueryPaginator(c.svc, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return dynamoError(fmt.Errorf("dynamodb query failed: %w", err))
+		}
+		if err := eachDevice(page.Items, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachDevice unmarshals a page of items and passes each device to fn.
+func eachDevice(items []map[string]types.AttributeValue, fn func(model.Device) error) error {
+	var devices []model.Device
+	if err := attributevalue.UnmarshalListOfMaps(items, &devices); err != nil {
+		return fmt.Errorf("failed to unmarshal devices: %w", err)
+	}
+
+	for _, device := range devices {
+		tidyDevice(&device)
+		if err := fn(device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dynamoError marks err as transient if the SDK classifies it as retryable or as
+// throttling. By then the SDK has already used up its own retry attempts.
+func dynamoError(err error) error {
+	if retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary ||
+		retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		return store.Transient(err)
+	}
+	return err
+}
+
+// deviceFromItem unmarshals an item returned by a write, or returns nil if there was none.
+func deviceFromItem(item map[string]types.AttributeValue) (*model.Device, error) {
+	if len(item) == 0 {
+		return nil, nil
+	}
+
+	var device model.Device
+	if err := attributevalue.UnmarshalMap(item, &device); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal item: %w", err)
+	}
+	tidyDevice(&device)
+	return &device, nil
+}
+
+// tidyDevice drops the empty Attributes map UpdateDevice leaves behind once the last
+// custom attribute is cleared, so devices read back the same as they were written.
+func tidyDevice(device *model.Device) {
+	if len(device.Attributes) == 0 {
+		device.Attributes = nil
+	}
+}
+
+// revisionAttribute is a counter on each device item that every write increments. Writes
+// read the item first and are conditioned on the revision they read, so the device and
+// its history event can be written in one transaction without losing a change made in
+// between. Items written before revisions existed have none until their next write.
+const revisionAttribute = "Revision"
+
+// dynamoWriteAttempts bounds how often a write re-reads a device that another write
+// changed between the read and the transaction.
+const dynamoWriteAttempts = 5
+
+// errDeviceRace reports that a device changed between being read and written.
+var errDeviceRace = errors.New("device changed while it was written")
+
+// errItemExists reports that an item a write adds to the transaction already exists.
+var errItemExists = errors.New("item already exists")
+
+// writeDevice reads the device consistently and passes it to write (nil if it does not
+// exist). write returns the device to store, or nil to delete it, and any other items to
+// write in the same transaction. The device, its history event and those items are
+// written together, and the whole write is retried if the device changed meanwhile. An
+// extra item whose condition fails makes writeDevice return errItemExists.
+func (c *DynamoClient) writeDevice(ctx context.Context, deviceID string, write func(before *model.Device) (*model.Device, []types.TransactWriteItem, error)) error {
+	for attempt := 0; ; attempt++ {
+		if attempt == dynamoWriteAttempts {
+			return store.Transient(fmt.Errorf("dynamodb write failed for ID %s: %w", deviceID, errDeviceRace))
+		}
+		if err := batchBackoff(ctx, attempt); err != nil {
+			return err
+		}
+
+		err := c.tryWriteDevice(ctx, deviceID, write)
+		if !errors.Is(err, errDeviceRace) {
+			return err
+		}
+	}
+}
+
+func (c *DynamoClient) tryWriteDevice(ctx context.Context, deviceID string, write func(before *model.Device) (*model.Device, []types.TransactWriteItem, error)) error {
+	out, err := c.svc.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.table),
+		Key:            deviceKey(deviceID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return dynamoError(fmt.Errorf("dynamodb get failed for ID %s: %w", deviceID, err))
+	}
+	before, err := deviceFromItem(out.Item)
+	if err != nil {
+		return err
 	}
 
-	updateExpressionParts := []string{}
-	attributeNames := map[string]string{}
-	attributeValues := map[string]types.AttributeValue{}
+	after, extra, err := write(before)
+	if err != nil {
+		return err
+	}
+	items, err := c.deviceWriteItems(ctx, out.Item, before, after)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return c.transact(ctx, deviceID, append(items, extra...), len(items))
+}
 
-	i := 0
-	for key, value := range updates {
-		namePlaceholder := fmt.Sprintf("#a%d", i)
-		valuePlaceholder := fmt.Sprintf(":v%d", i)
+// deviceWriteItems returns the transaction items that replace the device read as item
+// (nil if there was none) with after, or delete it if after is nil, followed by the
+// history event for the change. The device write is conditioned on the revision read.
+func (c *DynamoClient) deviceWriteItems(ctx context.Context, item map[string]types.AttributeValue, before, after *model.Device) ([]types.TransactWriteItem, error) {
+	if before == nil && after == nil {
+		return nil, nil
+	}
 
-		av, err := attributevalue.Marshal(value)
+	names := map[string]string{"#rev": revisionAttribute}
+	values := map[string]types.AttributeValue{}
+	var condition string
+	revision := int64(0)
+	switch av, ok := item[revisionAttribute]; {
+	case before == nil:
+		condition, names = "attribute_not_exists(AssetTag)", nil
+	case ok:
+		if err := attributevalue.Unmarshal(av, &revision); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", revisionAttribute, err)
+		}
+		condition = "#rev = :rev"
+		values[":rev"] = av
+	default:
+		condition = "attribute_exists(AssetTag) AND attribute_not_exists(#rev)"
+	}
+	if len(values) == 0 {
+		values = nil
+	}
+
+	var items []types.TransactWriteItem
+	var event model.DeviceEvent
+	if after == nil {
+		items = append(items, types.TransactWriteItem{Delete: &types.Delete{
+			TableName:                 aws.String(c.table),
+			Key:                       deviceKey(before.AssetTag),
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		}})
+		event = store.NewDeviceDeletedEvent(ctx, *before)
+	} else {
+		deviceItem, err := marshalDevice(*after, revision+1)
 		if err != nil {
-			return fmt.Errorf("failed to marshal update value for %s: %w", key, err)
+			return nil, err
+		}
+		items = append(items, types.TransactWriteItem{Put: &types.Put{
+			TableName:                 aws.String(c.table),
+			Item:                      deviceItem,
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		}})
+		if !store.ChangesDevice(before, *after) {
+			return items, nil
 		}
+		event = store.NewDeviceEvent(ctx, before, *after)
+	}
 
-		updateExpressionParts = append(updateExpressionParts, fmt.Sprintf("%s = %s", namePlaceholder, valuePlaceholder))
-		attributeNames[namePlaceholder] = key
-		attributeValues[valuePlaceholder] = av
-		i++
+	eventItem, err := attributevalue.MarshalMap(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
 	}
+	items = append(items, types.TransactWriteItem{Put: &types.Put{
+		TableName:           aws.String(c.eventsTable),
+		Item:                eventItem,
+		ConditionExpression: aws.String("attribute_not_exists(EventID)"),
+	}})
+	return items, nil
+}
 
-	updateExpression := "SET " + strings.Join(updateExpressionParts, ", ")
+// transact writes items in one transaction. A failed condition on one of the first
+// guarded items, the devices and their events, is reported as errDeviceRace, and on any
+// later item as errItemExists.
+func (c *DynamoClient) transact(ctx context.Context, deviceID string, items []types.TransactWriteItem, guarded int) error {
+	_, err := c.svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 
-	// --- 🛠️ THE CRITICAL FIX IS HERE ---
-	updateInput := &dynamodb.UpdateItemInput{
-		TableName: aws.String(tableName),
-		Key: map[string]types.AttributeValue{
-			// MUST be "SerialNumber" to match your CreateTable schema
-			"AssetTag": &types.AttributeValueMemberS{Value: deviceID},
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for i, reason := range canceled.CancellationReasons {
+			switch code := aws.ToString(reason.Code); {
+			case code == "TransactionConflict", code == "ConditionalCheckFailed" && i < guarded:
+				return errDeviceRace
+			case code == "ConditionalCheckFailed":
+				return errItemExists
+			case code == "ThrottlingError", code == "ProvisionedThroughputExceeded":
+				return store.Transient(fmt.Errorf("dynamodb transaction failed for ID %s: %w", deviceID, err))
+			}
+		}
+	}
+	if err != nil {
+		return dynamoError(fmt.Errorf("dynamodb transaction failed for ID %s: %w", deviceID, err))
+	}
+	return nil
+}
+
+// marshalDevice returns the item stored for a device at the given revision, with the
+// keys of the indexes it belongs in.
+func marshalDevice(device model.Device, revision int64) (map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMap(device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal item: %w", err)
+	}
+	item[revisionAttribute] = &types.AttributeValueMemberN{Value: strconv.FormatInt(revision, 10)}
+	if key := assigneeKey(device.AssignedTo); key != "" {
+		item[assigneeKeyAttribute] = &types.AttributeValueMemberS{Value: key}
+	}
+	if device.DueDate != nil {
+		item["DueDate"] = &types.AttributeValueMemberS{Value: device.DueDate.UTC().Format(dueDateLayout)}
+		item[dueShardAttribute] = &types.AttributeValueMemberS{Value: dueShard}
+	}
+	return item, nil
+}
+
+// assigneeKey returns the assignee index key for an assignee.
+func assigneeKey(assignee string) string {
+	return strings.ToLower(strings.TrimSpace(assignee))
+}
+
+// backfillIndexKeys rewrites the devices stored without the index keys marshalDevice
+// adds, such as those imported from a spreadsheet before the indexes existed, so that
+// index queries find them. Devices that cannot be rewritten are logged and skipped.
+func (c *DynamoClient) backfillIndexKeys(ctx context.Context) error {
+	var stale []string
+	err := c.scanPages(ctx, &dynamodb.ScanInput{
+		TableName:            aws.String(c.table),
+		ProjectionExpression: aws.String("AssetTag"),
+		FilterExpression: aws.String("(attribute_exists(AssignedTo) AND attribute_not_exists(#key)) OR " +
+			"(attribute_exists(DueDate) AND attribute_not_exists(#shard))"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":   assigneeKeyAttribute,
+			"#shard": dueShardAttribute,
+		},
+	}, func(device model.Device) error {
+		stale = append(stale, device.AssetTag)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, id := range stale {
+		// Writing the device back unchanged adds the keys, without a history event.
+		err := c.writeDevice(ctx, id, func(before *model.Device) (*model.Device, []types.TransactWriteItem, error) {
+			return before, nil, nil
+		})
+		if err != nil {
+			log.Printf("Warning: could not add index keys to device %s: %v", id, err)
+			continue
+		}
+		added++
+	}
+	if added > 0 {
+		log.Printf("Added index keys to %d devices in %s.", added, c.table)
+	}
+	return nil
+}
+
+// deviceKey returns the primary key of a device item.
+func deviceKey(deviceID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"AssetTag": &types.AttributeValueMemberS{Value: deviceID},
+	}
+}
+
+// ListDeviceEvents queries the device's partition of the events table, oldest first.
+func (c *DynamoClient) ListDeviceEvents(ctx context.Context, deviceID string) ([]model.DeviceEvent, error) {
+	paginator := dynamodb.NewQueryPaginator(c.svc, &dynamodb.QueryInput{
+		TableName:              aws.String(c.eventsTable),
+		KeyConditionExpression: aws.String("AssetTag = :tag"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":tag": &types.AttributeValueMemberS{Value: deviceID},
 		},
-		UpdateExpression:          aws.String(updateExpression),
-		ExpressionAttributeNames:  attributeNames,
-		ExpressionAttributeValues: attributeValues,
-		ReturnValues:              types.ReturnValueUpdatedNew,
+		ScanIndexForward: aws.Bool(true),
+	})
+
+	var events []model.DeviceEvent
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, dynamoError(fmt.Errorf("dynamodb query failed: %w", err))
+		}
+
+		var pageEvents []model.DeviceEvent
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageEvents); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal events: %w", err)
+		}
+		events = append(events, pageEvents...)
+	}
+	return events, nil
+}
+
+// IterateDeviceEvents scans the events table one page at a time.
+func (c *DynamoClient) IterateDeviceEvents(ctx context.Context, fn func(model.DeviceEvent) error) error {
+	paginator := dynamodb.NewScanPaginator(c.svc, &dynamodb.ScanInput{
+		TableName: aws.String(c.eventsTable),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return dynamoError(fmt.Errorf("dynamodb scan failed: %w", err))
+		}
+
+		var events []model.DeviceEvent
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &events); err != nil {
+			return fmt.Errorf("failed to unmarshal events: %w", err)
+		}
+		for _, e := range events {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Per-request limits of BatchWriteItem and BatchGetItem, and how many times a batch is
+// sent before giving up on the items DynamoDB left unprocessed.
+const (
+	dynamoBatchWriteSize = 25
+	dynamoBatchGetSize   = 100
+	dynamoBatchAttempts  = 8
+)
+
+// dynamoTransactDevices is how many devices BatchPutDevices writes per transaction. Each
+// takes two of the 100 items a transaction may hold: the device and its event.
+const dynamoTransactDevices = 50
+
+// BatchPutDevices writes the devices and their events in transactions of 50 devices,
+// each conditioned on the devices being unchanged since they were read. A batch larger
+// than that is not atomic as a whole.
+func (c *DynamoClient) BatchPutDevices(ctx context.Context, devices []model.Device) error {
+	latest := make(map[string]model.Device, len(devices))
+	var ids []string
+	for _, device := range devices {
+		if device.AssetTag == "" {
+			return fmt.Errorf("device is missing AssetTag")
+		}
+		if _, ok := latest[device.AssetTag]; !ok {
+			ids = append(ids, device.AssetTag)
+		}
+		// A transaction cannot write the same key twice.
+		latest[device.AssetTag] = device
 	}
 
-	_, err := c.svc.UpdateItem(ctx, updateInput)
+	for start := 0; start < len(ids); start += dynamoTransactDevices {
+		chunk := ids[start:min(start+dynamoTransactDevices, len(ids))]
+		for attempt := 0; ; attempt++ {
+			if attempt == dynamoWriteAttempts {
+				return store.Transient(fmt.Errorf("dynamodb batch put failed: %w", errDeviceRace))
+			}
+			if err := batchBackoff(ctx, attempt); err != nil {
+				return err
+			}
+
+			err := c.tryBatchPutDevices(ctx, chunk, latest)
+			if !errors.Is(err, errDeviceRace) {
+				if err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (c *DynamoClient) tryBatchPutDevices(ctx context.Context, ids []string, latest map[string]model.Device) error {
+	existing, err := c.batchGetItems(ctx, ids, true)
 	if err != nil {
-		return fmt.Errorf("dynamodb update failed for ID %s: %w", deviceID, err)
+		return err
+	}
+
+	var items []types.TransactWriteItem
+	for _, id := range ids {
+		before, err := deviceFromItem(existing[id])
+		if err != nil {
+			return err
+		}
+		after := latest[id]
+		deviceItems, err := c.deviceWriteItems(ctx, existing[id], before, &after)
+		if err != nil {
+			return err
+		}
+		items = append(items, deviceItems...)
 	}
+	return c.transact(ctx, ids[0], items, len(items))
+}
+
+// batchWrite sends requests to table in batches of dynamoBatchWriteSize.
+func (c *DynamoClient) batchWrite(ctx context.Context, table string, requests []types.WriteRequest) error {
+	for start := 0; start < len(requests); start += dynamoBatchWriteSize {
+		pending := map[string][]types.WriteRequest{
+			table: requests[start:min(start+dynamoBatchWriteSize, len(requests))],
+		}
+
+		for attempt := 0; len(pending[table]) > 0; attempt++ {
+			if attempt == dynamoBatchAttempts {
+				return store.Transient(fmt.Errorf("dynamodb batch write left %d items unprocessed", len(pending[table])))
+			}
+			if err := batchBackoff(ctx, attempt); err != nil {
+				return err
+			}
 
+			out, err := c.svc.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return dynamoError(fmt.Errorf("dynamodb batch write failed: %w", err))
+			}
+			pending = out.UnprocessedItems
+		}
+	}
 	return nil
 }
+
+// BatchGetDevices reads the devices with BatchGetItem, 100 at a time, resending any
+// keys DynamoDB leaves unprocessed.
+func (c *DynamoClient) BatchGetDevices(ctx context.Context, deviceIDs []string) ([]model.Device, error) {
+	ids := uniqueIDs(deviceIDs)
+	items, err := c.batchGetItems(ctx, ids, false)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]model.Device, 0, len(items))
+	for _, id := range ids {
+		device, err := deviceFromItem(items[id])
+		if err != nil {
+			return nil, err
+		}
+		if device != nil {
+			devices = append(devices, *device)
+		}
+	}
+	return devices, nil
+}
+
+// batchGetItems reads the device items with the given unique IDs, by asset tag. Devices
+// that do not exist are left out.
+func (c *DynamoClient) batchGetItems(ctx context.Context, ids []string, consistent bool) (map[string]map[string]types.AttributeValue, error) {
+	found := make(map[string]map[string]types.AttributeValue, len(ids))
+
+	for start := 0; start < len(ids); start += dynamoBatchGetSize {
+		chunk := ids[start:min(start+dynamoBatchGetSize, len(ids))]
+		keys := make([]map[string]types.AttributeValue, len(chunk))
+		for i, id := range chunk {
+			keys[i] = deviceKey(id)
+		}
+		pending := map[string]types.KeysAndAttributes{c.table: {Keys: keys, ConsistentRead: aws.Bool(consistent)}}
+
+		for attempt := 0; len(pending[c.table].Keys) > 0; attempt++ {
+			if attempt == dynamoBatchAttempts {
+				return nil, store.Transient(fmt.Errorf("dynamodb batch get left %d keys unprocessed", len(pending[c.table].Keys)))
+			}
+			if err := batchBackoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+
+			out, err := c.svc.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+			if err != nil {
+				return nil, dynamoError(fmt.Errorf("dynamodb batch get failed: %w", err))
+			}
+			for _, item := range out.Responses[c.table] {
+				var key struct{ AssetTag string }
+				if err := attributevalue.UnmarshalMap(item, &key); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal item: %w", err)
+				}
+				found[key.AssetTag] = item
+			}
+			pending = out.UnprocessedKeys
+		}
+	}
+	return found, nil
+}
+
+// batchBackoff waits before resending unprocessed batch items: nothing before the first
+// attempt, then a jittered delay that doubles with every attempt.
+func batchBackoff(ctx context.Context, attempt int) error {
+	if attempt == 0 {
+		return nil
+	}
+
+	ceiling := 50 * time.Millisecond << (attempt - 1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(rand.N(ceiling) + 1):
+		return nil
+	}
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+// reservationsTableDefinition describes the reservations table. Each device's
+// reservations share the AssetTag partition.
+func (c *DynamoClient) reservationsTableDefinition() *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(c.reservationsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("AssetTag"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("ReservationID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("AssetTag"),
+				KeyType:       types.KeyTypeHash,
+			},
+			{
+				AttributeName: aws.String("ReservationID"),
+				KeyType:       types.KeyTypeRange,
+			},
+		},
+	}
+}
+
+// CreateReservation checks the device and its reservations, then writes the reservation
+// together with the device, so that the write fails if the device changed or another
+// reservation was added since the check.
+func (c *DynamoClient) CreateReservation(ctx context.Context, r model.Reservation) error {
+	if err := store.ValidateReservation(r); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reservation: %w", err)
+	}
+
+	err = c.writeDevice(ctx, r.AssetTag, func(before *model.Device) (*model.Device, []types.TransactWriteItem, error) {
+		if before == nil {
+			return nil, nil, &store.NotFoundError{AssetTag: r.AssetTag}
+		}
+		existing, err := c.queryReservations(ctx, r.AssetTag)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := store.CheckReservation(*before, existing, r); err != nil {
+			return nil, nil, err
+		}
+
+		// The device is written back unchanged, which bumps its revision.
+		return before, []types.TransactWriteItem{{Put: &types.Put{
+			TableName:           aws.String(c.reservationsTable),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(ReservationID)"),
+		}}}, nil
+	})
+	if errors.Is(err, errItemExists) {
+		return &store.ReservationConflictError{AssetTag: r.AssetTag, Reservation: &r}
+	}
+	return err
+}
+
+// ListReservations queries one device's partition when the filter names a device and
+// scans the table otherwise, then checks each reservation with filter.Matches.
+func (c *DynamoClient) ListReservations(ctx context.Context, filter store.ReservationFilter) ([]model.Reservation, error) {
+	var all []model.Reservation
+	var err error
+	if filter.AssetTag != "" {
+		all, err = c.queryReservations(ctx, filter.AssetTag)
+	} else {
+		all, err = c.scanReservations(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reservations []model.Reservation
+	for _, r := range all {
+		if filter.Matches(r) {
+			reservations = append(reservations, r)
+		}
+	}
+	store.SortReservations(reservations)
+	return reservations, nil
+}
+
+// queryReservations reads every reservation of a device with a consistent read.
+func (c *DynamoClient) queryReservations(ctx context.Context, deviceID string) ([]model.Reservation, error) {
+	paginator := dynamodb.NewQueryPaginator(c.svc, &dynamodb.QueryInput{
+		TableName:              aws.String(c.reservationsTable),
+		KeyConditionExpression: aws.String("AssetTag = :tag"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":tag": &types.AttributeValueMemberS{Value: deviceID},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+
+	var reservations []model.Reservation
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, dynamoError(fmt.Errorf("dynamodb query failed: %w", err))
+		}
+		var pageReservations []model.Reservation
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageReservations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reservations: %w", err)
+		}
+		reservations = append(reservations, pageReservations...)
+	}
+	return reservations, nil
+}
+
+// scanReservations reads every reservation in the table.
+func (c *DynamoClient) scanReservations(ctx context.Context) ([]model.Reservation, error) {
+	paginator := dynamodb.NewScanPaginator(c.svc, &dynamodb.ScanInput{
+		TableName: aws.String(c.reservationsTable),
+	})
+
+	var reservations []model.Reservation
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, dynamoError(fmt.Errorf("dynamodb scan failed: %w", err))
+		}
+		var pageReservations []model.Reservation
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageReservations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reservations: %w", err)
+		}
+		reservations = append(reservations, pageReservations...)
+	}
+	return reservations, nil
+}
+
+// CancelReservation deletes the reservation item.
+func (c *DynamoClient) CancelReservation(ctx context.Context, deviceID, reservationID string) error {
+	_, err := c.svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(c.reservationsTable),
+		Key: map[string]types.AttributeValue{
+			"AssetTag":      &types.AttributeValueMemberS{Value: deviceID},
+			"ReservationID": &types.AttributeValueMemberS{Value: reservationID},
+		},
+		ConditionExpression: aws.String("attribute_exists(ReservationID)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return &store.ReservationNotFoundError{AssetTag: deviceID, ReservationID: reservationID}
+		}
+		return dynamoError(fmt.Errorf("dynamodb delete failed for reservation %s: %w", reservationID, err))
+	}
+	return nil
+}
+
+// deleteReservations removes every reservation of a deleted device. The device is already
+// gone by then, so a failure is logged rather than returned.
+func (c *DynamoClient) deleteReservations(ctx context.Context, deviceID string) {
+	reservations, err := c.queryReservations(ctx, deviceID)
+	if err == nil {
+		requests := make([]types.WriteRequest, 0, len(reservations))
+		for _, r := range reservations {
+			requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+				Key: map[string]types.AttributeValue{
+					"AssetTag":      &types.AttributeValueMemberS{Value: r.AssetTag},
+					"ReservationID": &types.AttributeValueMemberS{Value: r.ReservationID},
+				},
+			}})
+		}
+		err = c.batchWrite(ctx, c.reservationsTable, requests)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to delete the reservations of %s: %v", deviceID, err)
+	}
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"bdemetris/curator/pkg/store"
+	"bdemetris/curator/pkg/store/storetest"
+)
+
+// dynamoTestTables numbers the tables created by TestDynamoStore within a run.
+var dynamoTestTables atomic.Int64
+
+// TestDynamoStore runs against DynamoDB Local (see docker-compose.yml), e.g.
+//
+//	DYNAMODB_TEST_ENDPOINT=http://localhost:8000 go test ./pkg/database
+//
+// Every subtest creates its own tables and deletes them afterwards.
+func TestDynamoStore(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DYNAMODB_TEST_ENDPOINT is not set")
+	}
+
+	run := time.Now().Unix()
+	storetest.Run(t, func(t *testing.T) store.Store {
+		ctx := context.Background()
+		n := dynamoTestTables.Add(1)
+
+		s, err := NewDynamoStore(ctx, DynamoConfig{
+			Endpoint:          endpoint,
+			Table:             fmt.Sprintf("curator-test-%d-%d-devices", run, n),
+			EventsTable:       fmt.Sprintf("curator-test-%d-%d-events", run, n),
+			ReservationsTable: fmt.Sprintf("curator-test-%d-%d-reservations", run, n),
+			WaitlistTable:     fmt.Sprintf("curator-test-%d-%d-waitlist", run, n),
+		})
+		if err != nil {
+			t.Fatalf("NewDynamoStore: %v", err)
+		}
+
+		c := s.(*DynamoClient)
+		t.Cleanup(func() {
+			for _, table := range []string{c.table, c.eventsTable, c.reservationsTable, c.waitlistTable} {
+				if _, err := c.svc.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(table)}); err != nil {
+					t.Logf("failed to delete table %s: %v", table, err)
+				}
+			}
+		})
+		return s
+	})
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+// waitlistTableDefinition describes the waitlist table. The waitlist stays short, so it
+// is keyed by entry alone and read with a scan.
+func (c *DynamoClient) waitlistTableDefinition() *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(c.waitlistTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("EntryID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("EntryID"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+	}
+}
+
+// JoinWaitlist puts the entry unless one with its ID exists.
+func (c *DynamoClient) JoinWaitlist(ctx context.Context, e model.WaitlistEntry) error {
+	if err := store.ValidateWaitlistEntry(e); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal waitlist entry: %w", err)
+	}
+
+	_, err = c.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(c.waitlistTable),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(EntryID)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return &store.WaitlistConflictError{EntryID: e.EntryID}
+		}
+		return dynamoError(fmt.Errorf("dynamodb put failed for waitlist entry %s: %w", e.EntryID, err))
+	}
+	return nil
+}
+
+// ListWaitlist scans the table and checks each entry with filter.Matches.
+func (c *DynamoClient) ListWaitlist(ctx context.Context, filter store.WaitlistFilter) ([]model.WaitlistEntry, error) {
+	paginator := dynamodb.NewScanPaginator(c.svc, &dynamodb.ScanInput{
+		TableName:      aws.String(c.waitlistTable),
+		ConsistentRead: aws.Bool(true),
+	})
+
+	var entries []model.WaitlistEntry
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, dynamoError(fmt.Errorf("dynamodb scan failed: %w", err))
+		}
+		var pageEntries []model.WaitlistEntry
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageEntries); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal waitlist entries: %w", err)
+		}
+		for _, e := range pageEntries {
+			if filter.Matches(e) {
+				entries = append(entries, e)
+			}
+		}
+	}
+	store.SortWaitlist(entries)
+	return entries, nil
+}
+
+// OfferWaitlistEntry sets the offer with a condition that the entry exists and has none
+// yet, using the item returned by a failed condition to tell the two apart.
+func (c *DynamoClient) OfferWaitlistEntry(ctx context.Context, entryID string, offer model.WaitlistOffer) error {
+	if err := store.ValidateWaitlistOffer(offer); err != nil {
+		return err
+	}
+
+	av, err := attributevalue.Marshal(offer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal waitlist offer: %w", err)
+	}
+
+	_, err = c.svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(c.waitlistTable),
+		Key: map[string]types.AttributeValue{
+			"EntryID": &types.AttributeValueMemberS{Value: entryID},
+		},
+		UpdateExpression:                    aws.String("SET Offer = :offer"),
+		ConditionExpression:                 aws.String("attribute_exists(EntryID) AND attribute_not_exists(Offer)"),
+		ExpressionAttributeValues:           map[string]types.AttributeValue{":offer": av},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if !errors.As(err, &ccf) {
+			return dynamoError(fmt.Errorf("dynamodb update failed for waitlist entry %s: %w", entryID, err))
+		}
+		if len(ccf.Item) == 0 {
+			return &store.WaitlistEntryNotFoundError{EntryID: entryID}
+		}
+		var existing model.WaitlistEntry
+		if err := attributevalue.UnmarshalMap(ccf.Item, &existing); err != nil {
+			return fmt.Errorf("failed to unmarshal waitlist entry: %w", err)
+		}
+		return &store.WaitlistConflictError{EntryID: entryID, Offer: existing.Offer}
+	}
+	return nil
+}
+
+// LeaveWaitlist deletes the entry item.
+func (c *DynamoClient) LeaveWaitlist(ctx context.Context, entryID string) error {
+	_, err := c.svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(c.waitlistTable),
+		Key: map[string]types.AttributeValue{
+			"EntryID": &types.AttributeValueMemberS{Value: entryID},
+		},
+		ConditionExpression: aws.String("attribute_exists(EntryID)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return &store.WaitlistEntryNotFoundError{EntryID: entryID}
+		}
+		return dynamoError(fmt.Errorf("dynamodb delete failed for waitlist entry %s: %w", entryID, err))
+	}
+	return nil
+}
@@ -3,35 +3,70 @@ package database
 import (
 	"bdemetris/curator/pkg/model" // Use the shared data model
 	"bdemetris/curator/pkg/store" // Use the shared interface
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Assert that *JiraAssetsClient implements the store.Store interface
 var _ store.Store = (*JiraAssetsClient)(nil)
 
+// defaultJiraPageSize is the number of objects requested per AQL page.
+const defaultJiraPageSize = 50
+
 // JiraAssetsClient holds the necessary configuration for connecting to the Jira Assets API.
 type JiraAssetsClient struct {
-	BaseURL    string // e.g., "https://your-domain.atlassian.net/rest/insight/1.0"
+	BaseURL    string // e.g., "https://api.atlassian.com/jsm/assets/workspace/<id>/v1"
 	HTTPClient *http.Client
 	// Typically use API Token authentication:
 	APIToken string
 	Email    string
+
+	// Mapping describes which object type and attributes hold device data.
+	Mapping JiraDeviceMapping
+
+	// assignMu serializes checkouts, returns and retirements made through this client.
+	assignMu sync.Mutex
 }
 
 // NewJiraAssetsClient is the constructor for the Jira Assets Store implementation.
 // This function fulfills the store.Store interface requirement.
-func NewJiraAssetsClient(baseURL string, email string, apiToken string) (store.Store, error) {
+// The mapping is validated against the live schema before the client is returned.
+func NewJiraAssetsClient(ctx context.Context, baseURL string, email string, apiToken string, mapping JiraDeviceMapping) (store.Store, error) {
+	c, err := NewJiraAssetsAdminClient(baseURL, email, apiToken)
+	if err != nil {
+		return nil, err
+	}
+	c.Mapping = mapping
+
+	if err := c.ValidateMapping(ctx); err != nil {
+		return nil, fmt.Errorf("invalid Jira Assets mapping: %w", err)
+	}
+
+	return c, nil
+}
+
+// NewJiraAssetsAdminClient returns a client without a validated device mapping.
+// It is meant for schema discovery and other admin tooling, not as a store.Store.
+func NewJiraAssetsAdminClient(baseURL string, email string, apiToken string) (*JiraAssetsClient, error) {
 	if baseURL == "" || apiToken == "" {
 		return nil, fmt.Errorf("Jira Assets client requires BaseURL and APIToken")
 	}
 
 	return &JiraAssetsClient{
-		BaseURL:    baseURL,
-		HTTPClient: &http.Client{Timeout: 10},
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
 		APIToken:   apiToken,
 		Email:      email,
+		Mapping:    DefaultJiraDeviceMapping(),
 	}, nil
 }
 
@@ -42,22 +77,573 @@ func (c *JiraAssetsClient) Close() error {
 	return nil
 }
 
-// Placeholder for DynamoDB operations to satisfy the store.Store interface
+// PutDevice creates the device object, or overwrites every mapped attribute if it already exists.
 func (c *JiraAssetsClient) PutDevice(ctx context.Context, device model.Device) error {
-	return fmt.Errorf("Jira Assets client does not support PutDevice operation")
+	attrs, err := c.Mapping.attributesFromDevice(device, modifiedBy(ctx))
+	if err != nil {
+		return err
+	}
+
+	existing, found, err := c.findAsset(ctx, device.AssetTag)
+	if err != nil {
+		return err
+	}
+
+	if found {
+		return c.updateObject(ctx, existing.ID, attrs)
+	}
+
+	if _, ok := c.Mapping.Fields[FieldAssetTag]; !ok {
+		return store.Unsupported(store.ProviderJiraAssets, fmt.Sprintf("cannot create device %s: AssetTag must be mapped to a Jira attribute to create objects", device.AssetTag))
+	}
+	if c.Mapping.ObjectTypeID == "" {
+		return store.Unsupported(store.ProviderJiraAssets, fmt.Sprintf("cannot create device %s: no Jira object type ID configured", device.AssetTag))
+	}
+
+	body := jiraObjectIn{ObjectTypeID: c.Mapping.ObjectTypeID, Attributes: attrs}
+	if err := c.doJSON(ctx, http.MethodPost, "/object/create", body, nil); err != nil {
+		return fmt.Errorf("jira assets create failed for %s: %w", device.AssetTag, err)
+	}
+	return nil
+}
+
+// UpdateDevice sets or clears only the attributes of an existing device object that the
+// patch changes. Every changed field must be mapped to a Jira attribute.
+func (c *JiraAssetsClient) UpdateDevice(ctx context.Context, deviceID string, patch store.DevicePatch) error {
+	if err := patch.Validate(); err != nil {
+		return fmt.Errorf("invalid update for device ID %s: %w", deviceID, err)
+	}
+
+	updates := make(map[string]interface{})
+	for _, field := range patch.Fields() {
+		updates[field.Name] = field.Value
+		if field.Cleared {
+			updates[field.Name] = nil
+		}
+	}
+
+	return c.updateDevice(ctx, deviceID, updates)
+}
+
+// updateDevice writes the field updates to the device's object, along with who made them
+// when ModifiedBy is mapped. Every field must be mapped.
+func (c *JiraAssetsClient) updateDevice(ctx context.Context, deviceID string, updates map[string]interface{}) error {
+	attrs, err := c.Mapping.attributesFromUpdates(c.Mapping.withModifiedBy(updates, modifiedBy(ctx)), false)
+	if err != nil {
+		return err
+	}
+
+	existing, found, err := c.findAsset(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &store.NotFoundError{AssetTag: deviceID}
+	}
+
+	return c.updateObject(ctx, existing.ID, attrs)
 }
 
-// Placeholder for DynamoDB operations to satisfy the store.Store interface
-func (c *JiraAssetsClient) UpdateDevice(ctx context.Context, deviceID string, updates map[string]interface{}) error {
-	return fmt.Errorf("Jira Assets client does not support PutDevice operation")
+// BatchPutDevices writes the devices one at a time: the Assets API has no bulk write
+// outside of imports. It stops at the first failure.
+func (c *JiraAssetsClient) BatchPutDevices(ctx context.Context, devices []model.Device) error {
+	for _, device := range devices {
+		if err := c.PutDevice(ctx, device); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// GetAssetByKey retrieves a single Asset item by its object key (e.g., I-12345).
+// jiraBatchGetSize is the number of asset tags looked up per AQL query.
+const jiraBatchGetSize = 50
+
+// BatchGetDevices looks up the devices with one AQL "IN" query per jiraBatchGetSize asset tags.
+func (c *JiraAssetsClient) BatchGetDevices(ctx context.Context, deviceIDs []string) ([]model.Device, error) {
+	ids := uniqueIDs(deviceIDs)
+	found := make(map[string]model.Device, len(ids))
+
+	for start := 0; start < len(ids); start += jiraBatchGetSize {
+		chunk := ids[start:min(start+jiraBatchGetSize, len(ids))]
+		err := c.eachAsset(ctx, model.AssetSearchOptions{
+			ObjectSchemaID: c.Mapping.ObjectSchemaID,
+			AQL:            c.Mapping.devicesAQL() + " AND " + c.Mapping.lookupManyAQL(chunk),
+		}, func(asset model.JiraAsset) error {
+			device := c.Mapping.deviceFromAsset(asset)
+			found[device.AssetTag] = device
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	devices := make([]model.Device, 0, len(found))
+	for _, id := range ids {
+		if device, ok := found[id]; ok {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+// GetDevice retrieves a single device by its asset tag (by default the object key, e.g., I-12345).
 func (c *JiraAssetsClient) GetDevice(ctx context.Context, key string) (model.Device, error) {
-	return model.Device{}, fmt.Errorf("Jira API call for GetAssetByKey not yet implemented")
+	asset, found, err := c.findAsset(ctx, key)
+	if err != nil {
+		return model.Device{}, err
+	}
+
+	if !found {
+		return model.Device{}, &store.NotFoundError{AssetTag: key}
+	}
+
+	return c.Mapping.deviceFromAsset(asset), nil
 }
 
-// SearchAssets performs a search using Jira Assets Query Language (AQL).
+// ListDevices retrieves every device object from Jira Assets.
 func (c *JiraAssetsClient) ListDevices(ctx context.Context) ([]model.Device, error) {
-	return nil, fmt.Errorf("Jira API call for SearchAssets not yet implemented")
+	assets, err := c.SearchAssets(ctx, model.AssetSearchOptions{
+		ObjectSchemaID: c.Mapping.ObjectSchemaID,
+		AQL:            c.Mapping.devicesAQL(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]model.Device, 0, len(assets))
+	for _, asset := range assets {
+		devices = append(devices, c.Mapping.deviceFromAsset(asset))
+	}
+	return devices, nil
+}
+
+// IterateDevices passes every device object to fn, fetching one AQL page at a time.
+func (c *JiraAssetsClient) IterateDevices(ctx context.Context, fn func(model.Device) error) error {
+	return c.eachAsset(ctx, model.AssetSearchOptions{
+		ObjectSchemaID: c.Mapping.ObjectSchemaID,
+		AQL:            c.Mapping.devicesAQL(),
+	}, func(asset model.JiraAsset) error {
+		return fn(c.Mapping.deviceFromAsset(asset))
+	})
+}
+
+// --- Jira Assets REST API ---
+
+// findAsset looks up the device object with the given asset tag.
+func (c *JiraAssetsClient) findAsset(ctx context.Context, assetTag string) (model.JiraAsset, bool, error) {
+	assets, err := c.SearchAssets(ctx, model.AssetSearchOptions{
+		ObjectSchemaID: c.Mapping.ObjectSchemaID,
+		AQL:            c.Mapping.devicesAQL() + " AND " + c.Mapping.lookupAQL(assetTag),
+		ResultsPerPage: 1,
+	})
+	if err != nil {
+		return model.JiraAsset{}, false, err
+	}
+	if len(assets) == 0 {
+		return model.JiraAsset{}, false, nil
+	}
+	return assets[0], true, nil
+}
+
+// updateObject sets the given attributes on an existing object, leaving others untouched.
+func (c *JiraAssetsClient) updateObject(ctx context.Context, objectID string, attrs []jiraAttributeIn) error {
+	if c.Mapping.ObjectTypeID == "" {
+		return store.Unsupported(store.ProviderJiraAssets, fmt.Sprintf("cannot update object %s: no Jira object type ID configured", objectID))
+	}
+
+	body := jiraObjectIn{ObjectTypeID: c.Mapping.ObjectTypeID, Attributes: attrs}
+	if err := c.doJSON(ctx, http.MethodPut, "/object/"+url.PathEscape(objectID), body, nil); err != nil {
+		return fmt.Errorf("jira assets update failed for object %s: %w", objectID, err)
+	}
+	return nil
+}
+
+// SearchAssets performs a search using Jira Assets Query Language (AQL).
+// It pages through results from opts.StartAt, opts.ResultsPerPage objects at a time,
+// until every matching object has been read.
+func (c *JiraAssetsClient) SearchAssets(ctx context.Context, opts model.AssetSearchOptions) ([]model.JiraAsset, error) {
+	var assets []model.JiraAsset
+	err := c.eachAsset(ctx, opts, func(asset model.JiraAsset) error {
+		assets = append(assets, asset)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// eachAsset runs an AQL search like SearchAssets, passing each object to fn as its page arrives.
+func (c *JiraAssetsClient) eachAsset(ctx context.Context, opts model.AssetSearchOptions, fn func(model.JiraAsset) error) error {
+	aql := opts.AQL
+	if opts.ObjectSchemaID != "" {
+		aql = fmt.Sprintf("objectSchemaId = %s AND %s", quoteAQL(opts.ObjectSchemaID), aql)
+	}
+
+	pageSize := opts.ResultsPerPage
+	if pageSize <= 0 {
+		pageSize = defaultJiraPageSize
+	}
+
+	startAt := opts.StartAt
+	for {
+		query := url.Values{}
+		query.Set("startAt", strconv.Itoa(startAt))
+		query.Set("maxResults", strconv.Itoa(pageSize))
+		query.Set("includeAttributes", "true")
+
+		var page jiraAQLResponse
+		body := map[string]string{"qlQuery": aql}
+		if err := c.doJSON(ctx, http.MethodPost, "/object/aql?"+query.Encode(), body, &page); err != nil {
+			return fmt.Errorf("jira assets search failed: %w", err)
+		}
+
+		names := page.attributeNames()
+		for _, entry := range page.Values {
+			if err := fn(entry.toAsset(names)); err != nil {
+				return err
+			}
+		}
+
+		startAt += len(page.Values)
+		if page.IsLast || len(page.Values) == 0 || (page.Total > 0 && startAt >= page.Total) {
+			return nil
+		}
+	}
+}
+
+// doJSON sends an authenticated request to the Assets API and decodes the JSON response into out.
+func (c *JiraAssetsClient) doJSON(ctx context.Context, method, path string, in, out any) error {
+	var reqBody io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Email != "" {
+		req.SetBasicAuth(c.Email, c.APIToken)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.APIToken)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		// The request never got a response, e.g. a dropped connection or a client timeout.
+		return store.Transient(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("%s %s returned %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return &store.TransientError{Err: err, RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header given in seconds, as Jira sends it. Other forms are ignored.
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(header))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// jiraID accepts object and attribute IDs encoded either as JSON strings (Cloud) or numbers (Data Center).
+type jiraID string
+
+func (id *jiraID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = jiraID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid Jira ID %s", data)
+	}
+	*id = jiraID(n.String())
+	return nil
+}
+
+// jiraAQLResponse is one page of an AQL search.
+type jiraAQLResponse struct {
+	StartAt              int                       `json:"startAt"`
+	MaxResults           int                       `json:"maxResults"`
+	Total                int                       `json:"total"`
+	IsLast               bool                      `json:"isLast"`
+	Values               []jiraObjectEntry         `json:"values"`
+	ObjectTypeAttributes []jiraObjectTypeAttribute `json:"objectTypeAttributes"`
+}
+
+// attributeNames indexes the page's object type attributes by ID.
+func (r jiraAQLResponse) attributeNames() map[jiraID]string {
+	names := make(map[jiraID]string, len(r.ObjectTypeAttributes))
+	for _, attr := range r.ObjectTypeAttributes {
+		names[attr.ID] = attr.Name
+	}
+	return names
+}
+
+// jiraObjectTypeAttribute is the definition of an attribute on an object type.
+type jiraObjectTypeAttribute struct {
+	ID   jiraID `json:"id"`
+	Name string `json:"name"`
+}
+
+// jiraObjectEntry is a single object as returned by the Assets API.
+type jiraObjectEntry struct {
+	ID         jiraID `json:"id"`
+	Label      string `json:"label"`
+	ObjectKey  string `json:"objectKey"`
+	ObjectType struct {
+		ID             jiraID `json:"id"`
+		Name           string `json:"name"`
+		ObjectSchemaID jiraID `json:"objectSchemaId"`
+	} `json:"objectType"`
+	Created    string                `json:"created"`
+	Updated    string                `json:"updated"`
+	Attributes []jiraObjectAttribute `json:"attributes"`
+}
+
+// jiraObjectAttribute holds the values of one attribute on an object.
+type jiraObjectAttribute struct {
+	ObjectTypeAttributeID jiraID                   `json:"objectTypeAttributeId"`
+	ObjectTypeAttribute   *jiraObjectTypeAttribute `json:"objectTypeAttribute"`
+	ObjectAttributeValues []struct {
+		Value        any    `json:"value"`
+		DisplayValue string `json:"displayValue"`
+	} `json:"objectAttributeValues"`
+}
+
+// toAsset flattens an API object into a model.JiraAsset. Attribute names are taken from
+// the attribute itself when present and otherwise looked up in names.
+func (e jiraObjectEntry) toAsset(names map[jiraID]string) model.JiraAsset {
+	asset := model.JiraAsset{
+		Key:        e.ObjectKey,
+		ID:         string(e.ID),
+		Name:       e.Label,
+		ObjectType: e.ObjectType.Name,
+	}
+	if t, err := parseJiraTime(e.Created); err == nil {
+		asset.Created = t
+	}
+	if t, err := parseJiraTime(e.Updated); err == nil {
+		asset.Updated = t
+	}
+
+	for _, attr := range e.Attributes {
+		name := names[attr.ObjectTypeAttributeID]
+		if attr.ObjectTypeAttribute != nil && attr.ObjectTypeAttribute.Name != "" {
+			name = attr.ObjectTypeAttribute.Name
+		}
+
+		value := ""
+		if len(attr.ObjectAttributeValues) > 0 {
+			v := attr.ObjectAttributeValues[0]
+			if v.Value != nil {
+				value = fmt.Sprint(v.Value)
+			} else {
+				value = v.DisplayValue
+			}
+		}
+
+		asset.Attributes = append(asset.Attributes, model.JiraAssetAttribute{
+			ID:    string(attr.ObjectTypeAttributeID),
+			Name:  name,
+			Value: value,
+		})
+	}
+
+	return asset
+}
+
+// jiraObjectIn is the request body for creating or updating an object.
+type jiraObjectIn struct {
+	ObjectTypeID string            `json:"objectTypeId"`
+	Attributes   []jiraAttributeIn `json:"attributes"`
+}
+
+// jiraAttributeIn sets the values of one attribute. An empty value list clears it.
+type jiraAttributeIn struct {
+	ObjectTypeAttributeID string        `json:"objectTypeAttributeId"`
+	ObjectAttributeValues []jiraValueIn `json:"objectAttributeValues"`
+}
+
+// jiraValueIn is a single attribute value in a write request.
+type jiraValueIn struct {
+	Value string `json:"value"`
+}
+
+// CheckoutDevice assigns the device if its status allows a checkout and it is not held by
+// anyone but assignee. The Assets API has no conditional writes, so the check is only atomic within this process.
+func (c *JiraAssetsClient) CheckoutDevice(ctx context.Context, deviceID, assignee string, assignedAt, dueAt time.Time) error {
+	c.assignMu.Lock()
+	defer c.assignMu.Unlock()
+
+	current, err := c.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if err := store.CheckTransition(current, model.StatusCheckedOut); err != nil {
+		return err
+	}
+	if current.AssignedTo != "" && current.AssignedTo != assignee {
+		return &store.ConflictError{AssetTag: deviceID, AssignedTo: current.AssignedTo, DueDate: current.DueDate}
+	}
+
+	return c.updateDevice(ctx, deviceID, c.Mapping.withStatus(map[string]interface{}{
+		FieldAssignedTo:   assignee,
+		FieldAssignedDate: assignedAt,
+		FieldDueDate:      dueAt,
+	}, model.StatusCheckedOut))
+}
+
+// ReturnDevice clears the assignment if the device is checked out to assignee.
+// Like CheckoutDevice, it is only atomic within this process.
+func (c *JiraAssetsClient) ReturnDevice(ctx context.Context, deviceID, assignee string) error {
+	c.assignMu.Lock()
+	defer c.assignMu.Unlock()
+
+	current, err := c.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if current.AssignedTo != assignee {
+		return &store.ConflictError{AssetTag: deviceID, AssignedTo: current.AssignedTo, DueDate: current.DueDate}
+	}
+
+	return c.updateDevice(ctx, deviceID, c.Mapping.withStatus(map[string]interface{}{
+		FieldAssignedTo:   nil,
+		FieldAssignedDate: nil,
+		FieldDueDate:      nil,
+	}, model.StatusAvailable))
+}
+
+// DeleteDevice is unsupported: Jira Assets keeps no history for deleted objects, so the
+// device's history would be lost with it. Devices are retired instead.
+func (c *JiraAssetsClient) DeleteDevice(ctx context.Context, deviceID string) error {
+	_, found, err := c.findAsset(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &store.NotFoundError{AssetTag: deviceID}
+	}
+	return store.Unsupported(store.ProviderJiraAssets, "deleting an object loses its history, retire the device instead")
+}
+
+// SetDeviceStatus sets the mapped Status attribute if the device's current status allows
+// it, and clears any assignment if status ends a checkout. It is unsupported unless Status is mapped to a Jira
+// attribute, and like CheckoutDevice it is only atomic within this process.
+func (c *JiraAssetsClient) SetDeviceStatus(ctx context.Context, deviceID string, status model.DeviceStatus) error {
+	if err := store.ValidateStatus(status); err != nil {
+		return fmt.Errorf("invalid status for device ID %s: %w", deviceID, err)
+	}
+
+	c.assignMu.Lock()
+	defer c.assignMu.Unlock()
+
+	current, err := c.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if err := store.CheckTransition(current, status); err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{FieldStatus: string(status)}
+	if status.ClearsAssignment() {
+		fields[FieldAssignedTo] = nil
+		fields[FieldAssignedDate] = nil
+		fields[FieldDueDate] = nil
+	}
+	return c.updateDevice(ctx, deviceID, fields)
+}
+
+// CreateReservation is unsupported: Jira Assets has no place to keep reservations.
+func (c *JiraAssetsClient) CreateReservation(ctx context.Context, r model.Reservation) error {
+	return store.Unsupported(store.ProviderJiraAssets, "reservations are not stored in Jira Assets")
+}
+
+// ListReservations is unsupported, see CreateReservation.
+func (c *JiraAssetsClient) ListReservations(ctx context.Context, filter store.ReservationFilter) ([]model.Reservation, error) {
+	return nil, store.Unsupported(store.ProviderJiraAssets, "reservations are not stored in Jira Assets")
+}
+
+// CancelReservation is unsupported, see CreateReservation.
+func (c *JiraAssetsClient) CancelReservation(ctx context.Context, deviceID, reservationID string) error {
+	return store.Unsupported(store.ProviderJiraAssets, "reservations are not stored in Jira Assets")
+}
+
+// JoinWaitlist is unsupported: Jira Assets has no place to keep the waitlist.
+func (c *JiraAssetsClient) JoinWaitlist(ctx context.Context, e model.WaitlistEntry) error {
+	return store.Unsupported(store.ProviderJiraAssets, "the waitlist is not stored in Jira Assets")
+}
+
+// ListWaitlist is unsupported, see JoinWaitlist.
+func (c *JiraAssetsClient) ListWaitlist(ctx context.Context, filter store.WaitlistFilter) ([]model.WaitlistEntry, error) {
+	return nil, store.Unsupported(store.ProviderJiraAssets, "the waitlist is not stored in Jira Assets")
+}
+
+// OfferWaitlistEntry is unsupported, see JoinWaitlist.
+func (c *JiraAssetsClient) OfferWaitlistEntry(ctx context.Context, entryID string, offer model.WaitlistOffer) error {
+	return store.Unsupported(store.ProviderJiraAssets, "the waitlist is not stored in Jira Assets")
+}
+
+// LeaveWaitlist is unsupported, see JoinWaitlist.
+func (c *JiraAssetsClient) LeaveWaitlist(ctx context.Context, entryID string) error {
+	return store.Unsupported(store.ProviderJiraAssets, "the waitlist is not stored in Jira Assets")
+}
+
+// QueryDevices narrows the AQL search with the mapped attributes, then re-checks each
+// device with filter.Matches since AQL comparisons differ from the filter's semantics.
+func (c *JiraAssetsClient) QueryDevices(ctx context.Context, filter store.DeviceFilter) ([]model.Device, error) {
+	aql := c.Mapping.devicesAQL()
+	if clause := c.Mapping.filterAQL(filter); clause != "" {
+		aql += " AND " + clause
+	}
+
+	var devices []model.Device
+	err := c.eachAsset(ctx, model.AssetSearchOptions{
+		ObjectSchemaID: c.Mapping.ObjectSchemaID,
+		AQL:            aql,
+	}, func(asset model.JiraAsset) error {
+		device := c.Mapping.deviceFromAsset(asset)
+		if filter.Matches(device) {
+			devices = append(devices, device)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
 }
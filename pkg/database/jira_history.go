@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+// jiraHistoryCreated is the history entry type recorded when an object is created.
+const jiraHistoryCreated = 0
+
+// jiraHistoryEntry is one change in an object's history as returned by the Assets API.
+type jiraHistoryEntry struct {
+	ID    jiraID `json:"id"`
+	Actor struct {
+		DisplayName  string `json:"displayName"`
+		EmailAddress string `json:"emailAddress"`
+	} `json:"actor"`
+	AffectedAttribute string `json:"affectedAttribute"`
+	OldValue          string `json:"oldValue"`
+	NewValue          string `json:"newValue"`
+	Type              int    `json:"type"`
+	Created           string `json:"created"`
+}
+
+func (e jiraHistoryEntry) actor() string {
+	if e.Actor.EmailAddress != "" {
+		return e.Actor.EmailAddress
+	}
+	return e.Actor.DisplayName
+}
+
+// ListDeviceEvents rebuilds a device's history from the object history Jira Assets keeps
+// for every object, oldest first. Jira records the account that made each change, which
+// for changes made through the bot is the bot's own. When ModifiedBy is mapped, those
+// changes are attributed to the Slack user the bot wrote there instead, along with the
+// write ID, see store.WithWriteID.
+// Entries made by the same actor at the same moment are treated as a single event.
+func (c *JiraAssetsClient) ListDeviceEvents(ctx context.Context, deviceID string) ([]model.DeviceEvent, error) {
+	asset, found, err := c.findAsset(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	return c.assetEvents(ctx, deviceID, asset)
+}
+
+// IterateDeviceEvents rebuilds the history of each device object in turn, one request
+// per device. Jira Assets has no history of deleted objects, so they have no events.
+func (c *JiraAssetsClient) IterateDeviceEvents(ctx context.Context, fn func(model.DeviceEvent) error) error {
+	return c.eachAsset(ctx, model.AssetSearchOptions{
+		ObjectSchemaID: c.Mapping.ObjectSchemaID,
+		AQL:            c.Mapping.devicesAQL(),
+	}, func(asset model.JiraAsset) error {
+		events, err := c.assetEvents(ctx, c.Mapping.deviceFromAsset(asset).AssetTag, asset)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// assetEvents rebuilds the history of the device held by asset, oldest first.
+func (c *JiraAssetsClient) assetEvents(ctx context.Context, deviceID string, asset model.JiraAsset) ([]model.DeviceEvent, error) {
+	var entries []jiraHistoryEntry
+	path := "/object/" + url.PathEscape(asset.ID) + "/history?asc=true"
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &entries); err != nil {
+		return nil, fmt.Errorf("jira assets history failed for %s: %w", deviceID, err)
+	}
+
+	fields := make(map[string]string, len(c.Mapping.Fields))
+	modifiedBy := ""
+	for field, ref := range c.Mapping.Fields {
+		if ref.Name != "" {
+			fields[strings.ToLower(ref.Name)] = field
+		}
+	}
+
+	var groups [][]jiraHistoryEntry
+	for i, entry := range entries {
+		if i > 0 && entry.Created == entries[i-1].Created && entry.actor() == entries[i-1].actor() {
+			groups[len(groups)-1] = append(groups[len(groups)-1], entry)
+			continue
+		}
+		groups = append(groups, []jiraHistoryEntry{entry})
+	}
+
+	// Walk backwards from the current state, undoing each group to find the state before it.
+	// modifiedBy follows the ModifiedBy attribute the same way, so a bot write that left it
+	// unchanged is still attributed to whoever it names.
+	state := c.Mapping.deviceFromAsset(asset)
+	if ref, ok := c.Mapping.Fields[FieldModifiedBy]; ok {
+		modifiedBy, _ = asset.Attribute(ref.key())
+	}
+	var events []model.DeviceEvent
+	for i := len(groups) - 1; i >= 0; i-- {
+		group := groups[i]
+		after := state.Clone()
+		before := state.Clone()
+
+		actor, writeID := group[0].actor(), ""
+		if modifiedBy != "" && strings.EqualFold(group[0].Actor.EmailAddress, c.Email) {
+			actor, writeID = parseModifiedBy(modifiedBy)
+		}
+
+		created := false
+		for _, entry := range group {
+			if entry.Type == jiraHistoryCreated {
+				created = true
+			}
+			field, ok := fields[strings.ToLower(entry.AffectedAttribute)]
+			switch {
+			case !ok:
+			case field == FieldModifiedBy:
+				// Jira may not name the bot account, so the value written is authoritative.
+				if entry.NewValue != "" {
+					actor, writeID = parseModifiedBy(entry.NewValue)
+				}
+				modifiedBy = entry.OldValue
+			default:
+				setDeviceField(&before, field, entry.OldValue)
+			}
+		}
+
+		timestamp, _ := parseJiraTime(group[0].Created)
+		event := model.DeviceEvent{
+			AssetTag:  after.AssetTag,
+			EventID:   store.DeviceEventID(timestamp, string(group[0].ID)),
+			Actor:     actor,
+			Timestamp: timestamp,
+			After:     &after,
+			WriteID:   writeID,
+		}
+
+		if !created {
+			if len(model.DiffDevices(before, after)) == 0 {
+				state = before
+				continue
+			}
+			event.Before = &before
+		}
+		event.Type = model.ClassifyDeviceChange(event.Before, after)
+
+		events = append(events, event)
+		state = before
+	}
+
+	// events were collected newest first.
+	slices.Reverse(events)
+	return events, nil
+}
+
+// modifiedBy returns the ModifiedBy value written with ctx: its actor, followed by its
+// write ID if it has one, e.g. "ada@example.com (write 1f2e3d4c)".
+func modifiedBy(ctx context.Context) string {
+	value := store.ActorFromContext(ctx)
+	if id := store.WriteIDFromContext(ctx); id != "" {
+		value += " (write " + id + ")"
+	}
+	return value
+}
+
+// parseModifiedBy splits a ModifiedBy value into the actor and write ID.
+func parseModifiedBy(value string) (actor, writeID string) {
+	if rest, ok := strings.CutSuffix(value, ")"); ok {
+		if actor, writeID, ok := strings.Cut(rest, " (write "); ok {
+			return actor, writeID
+		}
+	}
+	return value, ""
+}
+
+// setDeviceField sets a mapped Device field from its Jira attribute value.
+func setDeviceField(d *model.Device, field, value string) {
+	if key, ok := model.ParseAttributeField(field); ok {
+		attrs := maps.Clone(d.Attributes)
+		if attrs == nil {
+			attrs = make(map[string]string)
+		}
+		attrs[key] = value
+		if value == "" {
+			delete(attrs, key)
+		}
+		if len(attrs) == 0 {
+			attrs = nil
+		}
+		d.Attributes = attrs
+		return
+	}
+
+	if isDateField(field) {
+		var t *time.Time
+		if parsed, err := parseJiraTime(value); err == nil {
+			t = &parsed
+		}
+		switch field {
+		case FieldAssignedDate:
+			d.AssignedDate = t
+		case FieldDueDate:
+			d.DueDate = t
+		case FieldPurchaseDate:
+			d.PurchaseDate = t
+		case FieldWarrantyExpiration:
+			d.WarrantyExpiration = t
+		}
+		return
+	}
+
+	switch field {
+	case FieldAssetTag:
+		d.AssetTag = value
+	case FieldDeviceType:
+		d.DeviceType = value
+	case FieldDeviceMake:
+		d.DeviceMake = value
+	case FieldDeviceModel:
+		d.DeviceModel = value
+	case FieldLocation:
+		d.Location = value
+	case FieldAssignedTo:
+		d.AssignedTo = value
+	case FieldStatus:
+		d.Status = jiraStatus(value)
+	case FieldSerialNumber:
+		d.SerialNumber = value
+	case FieldVendor:
+		d.Vendor = value
+	case FieldPurchaseCost:
+		d.PurchaseCost = jiraCost(value)
+	}
+}
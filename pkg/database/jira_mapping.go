@@ -0,0 +1,527 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+// Device field names used as keys in JiraDeviceMapping.Fields.
+const (
+	FieldAssetTag     = "AssetTag"
+	FieldDeviceType   = "DeviceType"
+	FieldDeviceMake   = "DeviceMake"
+	FieldDeviceModel  = "DeviceModel"
+	FieldLocation     = "Location"
+	FieldAssignedTo   = "AssignedTo"
+	FieldAssignedDate = "AssignedDate"
+	FieldDueDate      = "DueDate"
+	FieldStatus       = "Status"
+
+	FieldSerialNumber       = "SerialNumber"
+	FieldVendor             = "Vendor"
+	FieldPurchaseDate       = "PurchaseDate"
+	FieldPurchaseCost       = "PurchaseCost"
+	FieldWarrantyExpiration = "WarrantyExpiration"
+
+	// FieldModifiedBy is not a Device field: it maps an attribute the bot sets to the
+	// Slack user behind each write and the write's ID, so that history can name them
+	// instead of the bot.
+	FieldModifiedBy = "ModifiedBy"
+)
+
+// deviceFields lists every model.Device field that can be mapped to a Jira attribute.
+var deviceFields = []string{
+	FieldAssetTag, FieldDeviceType, FieldDeviceMake, FieldDeviceModel,
+	FieldLocation, FieldAssignedTo, FieldAssignedDate, FieldDueDate, FieldStatus,
+	FieldSerialNumber, FieldVendor, FieldPurchaseDate, FieldPurchaseCost, FieldWarrantyExpiration,
+}
+
+// isMappableField reports whether a field name can be a key in JiraDeviceMapping.Fields:
+// one of deviceFields, FieldModifiedBy, or a custom attribute named with model.AttributeField.
+func isMappableField(field string) bool {
+	if key, ok := model.ParseAttributeField(field); ok {
+		return key != ""
+	}
+	return field == FieldModifiedBy || slices.Contains(deviceFields, field)
+}
+
+// JiraDeviceMapping describes where each model.Device field lives in Jira Assets.
+// It is usually loaded from a JSON file with LoadJiraDeviceMapping.
+type JiraDeviceMapping struct {
+	ObjectSchemaID string `json:"objectSchemaId,omitempty"` // Restricts searches to a single schema when set
+	ObjectTypeID   string `json:"objectTypeId,omitempty"`   // ID of the object type holding devices, required to create devices
+	ObjectType     string `json:"objectType,omitempty"`     // Name of the object type holding devices (e.g., "Device")
+
+	// Fields maps a model.Device field name to the Jira attribute holding it. Custom
+	// attributes are mapped by their model.AttributeField name, e.g. "Attributes.carrier".
+	// When AssetTag is unmapped, the object key is used as the asset tag.
+	Fields map[string]JiraAttributeRef `json:"fields"`
+}
+
+// JiraAttributeRef identifies an object type attribute by ID, name, or both.
+// Reads accept either; writes require the ID, and AQL lookups by asset tag require the name.
+type JiraAttributeRef struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// key returns the identifier used to find the attribute on a fetched asset.
+func (r JiraAttributeRef) key() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Name
+}
+
+// DefaultJiraDeviceMapping returns the mapping used when none is configured.
+func DefaultJiraDeviceMapping() JiraDeviceMapping {
+	return JiraDeviceMapping{
+		ObjectType: "Device",
+		Fields: map[string]JiraAttributeRef{
+			FieldDeviceType:   {Name: "Device Type"},
+			FieldDeviceMake:   {Name: "Make"},
+			FieldDeviceModel:  {Name: "Model"},
+			FieldLocation:     {Name: "Location"},
+			FieldAssignedTo:   {Name: "Assigned To"},
+			FieldAssignedDate: {Name: "Assigned Date"},
+			FieldDueDate:      {Name: "Due Date"},
+		},
+	}
+}
+
+// isDateField reports whether the Device field holds a timestamp.
+func isDateField(field string) bool {
+	switch field {
+	case FieldAssignedDate, FieldDueDate, FieldPurchaseDate, FieldWarrantyExpiration:
+		return true
+	}
+	return false
+}
+
+// fieldNameHints are lower-case attribute names that commonly hold each Device field,
+// in order of preference. They drive SuggestJiraDeviceMapping.
+var fieldNameHints = map[string][]string{
+	FieldAssetTag:     {"asset tag", "assettag", "tag", "asset number"},
+	FieldDeviceType:   {"device type", "type", "category"},
+	FieldDeviceMake:   {"make", "manufacturer", "brand"},
+	FieldDeviceModel:  {"model", "model name"},
+	FieldLocation:     {"location", "site", "office"},
+	FieldAssignedTo:   {"assigned to", "assignee", "owner", "user"},
+	FieldAssignedDate: {"assigned date", "checkout date", "checked out", "assigned on"},
+	FieldDueDate:      {"due date", "return date", "due"},
+	FieldStatus:       {"lifecycle status", "device status"},
+
+	FieldSerialNumber:       {"serial number", "serial", "serial no", "s/n"},
+	FieldVendor:             {"vendor", "supplier", "reseller"},
+	FieldPurchaseDate:       {"purchase date", "purchased", "date purchased", "order date"},
+	FieldPurchaseCost:       {"purchase cost", "cost", "purchase price", "price"},
+	FieldWarrantyExpiration: {"warranty expiration", "warranty expiry", "warranty end date", "warranty end", "warranty"},
+}
+
+// SuggestJiraDeviceMapping guesses a mapping for an object type from its attribute names.
+// Fields without a plausible attribute are left unmapped for the user to fill in.
+func SuggestJiraDeviceMapping(objectType model.JiraObjectType, attrs []model.JiraObjectTypeAttribute) JiraDeviceMapping {
+	mapping := JiraDeviceMapping{
+		ObjectSchemaID: objectType.ObjectSchemaID,
+		ObjectTypeID:   objectType.ID,
+		ObjectType:     objectType.Name,
+		Fields:         make(map[string]JiraAttributeRef),
+	}
+
+	used := make(map[string]bool)
+	for _, field := range deviceFields {
+		for _, hint := range fieldNameHints[field] {
+			attr, ok := findAttribute(attrs, JiraAttributeRef{Name: hint})
+			if !ok || used[attr.ID] {
+				continue
+			}
+			if isDateField(field) && attr.Type != "Date" && attr.Type != "DateTime" {
+				continue
+			}
+			if field == FieldPurchaseCost && attr.Type == "Integer" {
+				continue
+			}
+			used[attr.ID] = true
+			mapping.Fields[field] = JiraAttributeRef{ID: attr.ID, Name: attr.Name}
+			break
+		}
+	}
+
+	return mapping
+}
+
+// LoadJiraDeviceMapping reads a mapping from a JSON file. An empty path returns the default mapping.
+func LoadJiraDeviceMapping(path string) (JiraDeviceMapping, error) {
+	if path == "" {
+		return DefaultJiraDeviceMapping(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return JiraDeviceMapping{}, fmt.Errorf("failed to read Jira mapping file: %w", err)
+	}
+
+	var mapping JiraDeviceMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return JiraDeviceMapping{}, fmt.Errorf("failed to parse Jira mapping file %s: %w", path, err)
+	}
+
+	for field := range mapping.Fields {
+		if !isMappableField(field) {
+			return JiraDeviceMapping{}, fmt.Errorf("unknown device field %q in Jira mapping file %s", field, path)
+		}
+	}
+	if mapping.ObjectType == "" && mapping.ObjectTypeID == "" {
+		return JiraDeviceMapping{}, fmt.Errorf("Jira mapping file %s must set objectType or objectTypeId", path)
+	}
+
+	return mapping, nil
+}
+
+// WithAttributeIDs returns a copy of the mapping with attribute IDs set from a spec of
+// comma-separated Field=ID pairs (e.g., "AssetTag=135,AssignedTo=140").
+func (m JiraDeviceMapping) WithAttributeIDs(spec string) (JiraDeviceMapping, error) {
+	fields := make(map[string]JiraAttributeRef, len(m.Fields))
+	for field, ref := range m.Fields {
+		fields[field] = ref
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		field, id, ok := strings.Cut(pair, "=")
+		field, id = strings.TrimSpace(field), strings.TrimSpace(id)
+		if !ok || id == "" {
+			return m, fmt.Errorf("invalid Jira attribute ID mapping %q, expected Field=ID", pair)
+		}
+		if !isMappableField(field) {
+			return m, fmt.Errorf("unknown device field %q in Jira attribute ID mapping", field)
+		}
+
+		ref := fields[field]
+		ref.ID = id
+		fields[field] = ref
+	}
+
+	m.Fields = fields
+	return m, nil
+}
+
+// jiraTimeLayouts are the date formats returned by the Assets API for Date and DateTime attributes.
+var jiraTimeLayouts = []string{
+	"2006-01-02T15:04:05.000Z0700",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+// parseJiraTime parses a Jira Assets date or datetime value.
+func parseJiraTime(value string) (time.Time, error) {
+	for _, layout := range jiraTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized Jira date %q", value)
+}
+
+// deviceFromAsset converts a Jira Asset into a Device using the mapping.
+func (m JiraDeviceMapping) deviceFromAsset(asset model.JiraAsset) model.Device {
+	text := func(field string) string {
+		ref, ok := m.Fields[field]
+		if !ok {
+			return ""
+		}
+		value, _ := asset.Attribute(ref.key())
+		return value
+	}
+
+	date := func(field string) *time.Time {
+		value := text(field)
+		if value == "" {
+			return nil
+		}
+		t, err := parseJiraTime(value)
+		if err != nil {
+			return nil
+		}
+		return &t
+	}
+
+	device := model.Device{
+		AssetTag:     text(FieldAssetTag),
+		DeviceType:   text(FieldDeviceType),
+		DeviceMake:   text(FieldDeviceMake),
+		DeviceModel:  text(FieldDeviceModel),
+		Location:     text(FieldLocation),
+		AssignedTo:   text(FieldAssignedTo),
+		AssignedDate: date(FieldAssignedDate),
+		DueDate:      date(FieldDueDate),
+		Status:       jiraStatus(text(FieldStatus)),
+
+		SerialNumber:       text(FieldSerialNumber),
+		Vendor:             text(FieldVendor),
+		PurchaseDate:       date(FieldPurchaseDate),
+		PurchaseCost:       jiraCost(text(FieldPurchaseCost)),
+		WarrantyExpiration: date(FieldWarrantyExpiration),
+	}
+	if _, ok := m.Fields[FieldAssetTag]; !ok {
+		device.AssetTag = asset.Key
+	}
+
+	for _, field := range m.attributeFields() {
+		if value := text(field); value != "" {
+			key, _ := model.ParseAttributeField(field)
+			if device.Attributes == nil {
+				device.Attributes = make(map[string]string)
+			}
+			device.Attributes[key] = value
+		}
+	}
+	return device
+}
+
+// attributeFields returns the mapped custom attribute fields, sorted.
+func (m JiraDeviceMapping) attributeFields() []string {
+	var fields []string
+	for field := range m.Fields {
+		if _, ok := model.ParseAttributeField(field); ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// mappedFields returns every mapped field, fixed fields first in deviceFields order and
+// FieldModifiedBy last.
+func (m JiraDeviceMapping) mappedFields() []string {
+	var fields []string
+	for _, field := range deviceFields {
+		if _, ok := m.Fields[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	fields = append(fields, m.attributeFields()...)
+	if _, ok := m.Fields[FieldModifiedBy]; ok {
+		fields = append(fields, FieldModifiedBy)
+	}
+	return fields
+}
+
+// jiraStatus parses a Status attribute value. Values curator did not write are left
+// empty, so the device's status is derived from its assignment.
+func jiraStatus(value string) model.DeviceStatus {
+	status, err := model.ParseDeviceStatus(value)
+	if err != nil {
+		return ""
+	}
+	return status
+}
+
+// jiraCost parses a PurchaseCost attribute value into cents. Values that are not an
+// amount are treated as unknown.
+func jiraCost(value string) int64 {
+	cents, err := model.ParseCost(value)
+	if err != nil {
+		return 0
+	}
+	return cents
+}
+
+// withStatus adds status to updates if the Status field is mapped. Without it, a device's
+// status is derived from its assignment, see model.Device.CurrentStatus.
+func (m JiraDeviceMapping) withStatus(updates map[string]interface{}, status model.DeviceStatus) map[string]interface{} {
+	if _, ok := m.Fields[FieldStatus]; ok {
+		updates[FieldStatus] = string(status)
+	}
+	return updates
+}
+
+// withModifiedBy adds the ModifiedBy value to updates if the ModifiedBy field is mapped.
+func (m JiraDeviceMapping) withModifiedBy(updates map[string]interface{}, value string) map[string]interface{} {
+	if _, ok := m.Fields[FieldModifiedBy]; ok {
+		updates[FieldModifiedBy] = value
+	}
+	return updates
+}
+
+// lookupAQL returns the AQL clause selecting a device by its asset tag.
+func (m JiraDeviceMapping) lookupAQL(assetTag string) string {
+	if ref, ok := m.Fields[FieldAssetTag]; ok && ref.Name != "" {
+		return fmt.Sprintf("%s = %s", quoteAQL(ref.Name), quoteAQL(assetTag))
+	}
+	return fmt.Sprintf("Key = %s", quoteAQL(assetTag))
+}
+
+// lookupManyAQL returns the AQL clause selecting the devices with any of the asset tags.
+func (m JiraDeviceMapping) lookupManyAQL(assetTags []string) string {
+	quoted := make([]string, len(assetTags))
+	for i, tag := range assetTags {
+		quoted[i] = quoteAQL(tag)
+	}
+
+	attr := "Key"
+	if ref, ok := m.Fields[FieldAssetTag]; ok && ref.Name != "" {
+		attr = quoteAQL(ref.Name)
+	}
+	return fmt.Sprintf("%s IN (%s)", attr, strings.Join(quoted, ", "))
+}
+
+// filterAQL returns the AQL clauses for the parts of filter whose attributes are mapped by name.
+func (m JiraDeviceMapping) filterAQL(filter store.DeviceFilter) string {
+	var clauses []string
+	attr := func(field string) (string, bool) {
+		ref, ok := m.Fields[field]
+		if !ok || ref.Name == "" {
+			return "", false
+		}
+		return quoteAQL(ref.Name), true
+	}
+
+	equals := map[string]string{
+		FieldAssignedTo: filter.AssignedTo,
+		FieldDeviceType: filter.DeviceType,
+		FieldLocation:   filter.Location,
+	}
+	for _, field := range deviceFields {
+		value := strings.TrimSpace(equals[field])
+		if name, ok := attr(field); ok && value != "" {
+			clauses = append(clauses, fmt.Sprintf("%s = %s", name, quoteAQL(value)))
+		}
+	}
+
+	if name, ok := attr(FieldAssignedTo); ok {
+		switch filter.Availability {
+		case store.OnlyAvailable:
+			clauses = append(clauses, name+" IS EMPTY")
+		case store.OnlyCheckedOut:
+			clauses = append(clauses, name+" IS NOT EMPTY")
+		}
+	}
+	if name, ok := attr(FieldDueDate); ok && filter.DueBefore != nil {
+		clauses = append(clauses, name+" IS NOT EMPTY")
+	}
+	if name, ok := attr(FieldWarrantyExpiration); ok && filter.WarrantyBefore != nil {
+		clauses = append(clauses, name+" IS NOT EMPTY")
+	}
+
+	keys := make([]string, 0, len(filter.Attributes))
+	for key := range filter.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field := model.AttributeField(strings.ToLower(strings.TrimSpace(key)))
+		value := strings.TrimSpace(filter.Attributes[key])
+		if name, ok := attr(field); ok && value != "" {
+			clauses = append(clauses, fmt.Sprintf("%s = %s", name, quoteAQL(value)))
+		}
+	}
+
+	return strings.Join(clauses, " AND ")
+}
+
+// devicesAQL returns the AQL clause selecting every device object.
+func (m JiraDeviceMapping) devicesAQL() string {
+	return fmt.Sprintf("objectType = %s", quoteAQL(m.ObjectType))
+}
+
+// attributesFromDevice returns the attribute values for every mapped field of the device,
+// and the ModifiedBy value. Mapped custom attributes the device does not have are cleared.
+func (m JiraDeviceMapping) attributesFromDevice(device model.Device, modifiedBy string) ([]jiraAttributeIn, error) {
+	updates := map[string]interface{}{
+		FieldAssetTag:     device.AssetTag,
+		FieldDeviceType:   device.DeviceType,
+		FieldDeviceMake:   device.DeviceMake,
+		FieldDeviceModel:  device.DeviceModel,
+		FieldLocation:     device.Location,
+		FieldAssignedTo:   device.AssignedTo,
+		FieldAssignedDate: device.AssignedDate,
+		FieldDueDate:      device.DueDate,
+		FieldStatus:       string(device.Status),
+
+		FieldSerialNumber:       device.SerialNumber,
+		FieldVendor:             device.Vendor,
+		FieldPurchaseDate:       device.PurchaseDate,
+		FieldPurchaseCost:       device.PurchaseCost,
+		FieldWarrantyExpiration: device.WarrantyExpiration,
+	}
+	for _, field := range m.attributeFields() {
+		key, _ := model.ParseAttributeField(field)
+		updates[field] = device.Attributes[key]
+	}
+	return m.attributesFromUpdates(m.withModifiedBy(updates, modifiedBy), true)
+}
+
+// attributesFromUpdates converts field updates into attribute values. Fields without a
+// mapping are an error unless skipUnmapped is set.
+func (m JiraDeviceMapping) attributesFromUpdates(updates map[string]interface{}, skipUnmapped bool) ([]jiraAttributeIn, error) {
+	attrs := make([]jiraAttributeIn, 0, len(updates))
+	for field, value := range updates {
+		ref, ok := m.Fields[field]
+		if !ok {
+			if skipUnmapped {
+				continue
+			}
+			return nil, store.Unsupported(store.ProviderJiraAssets, "no Jira attribute is mapped for field "+field)
+		}
+		if ref.ID == "" {
+			return nil, store.Unsupported(store.ProviderJiraAssets, "Jira attribute for field "+field+" has no ID configured")
+		}
+
+		text, err := jiraValueString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for field %s: %w", field, err)
+		}
+
+		attr := jiraAttributeIn{ObjectTypeAttributeID: ref.ID, ObjectAttributeValues: []jiraValueIn{}}
+		if text != "" {
+			attr.ObjectAttributeValues = append(attr.ObjectAttributeValues, jiraValueIn{Value: text})
+		}
+		attrs = append(attrs, attr)
+	}
+
+	// Keep request bodies stable regardless of map iteration order.
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].ObjectTypeAttributeID < attrs[j].ObjectTypeAttributeID })
+	return attrs, nil
+}
+
+// jiraValueString formats a Device field value as a Jira attribute value. An empty
+// result clears the attribute.
+func jiraValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int64: // PurchaseCost, in cents
+		return model.FormatCost(v), nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.UTC().Format(time.RFC3339), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// quoteAQL wraps a value in double quotes, escaping embedded quotes and backslashes.
+func quoteAQL(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// jiraReferenceTypes names the non-default attribute types by their numeric code.
+var jiraReferenceTypes = map[int]string{
+	1: "Object",
+	2: "User",
+	3: "Confluence",
+	4: "Group",
+	5: "Version",
+	6: "Project",
+	7: "Status",
+}
+
+// ListObjectSchemas returns every object schema visible to the client.
+func (c *JiraAssetsClient) ListObjectSchemas(ctx context.Context) ([]model.JiraObjectSchema, error) {
+	var schemas []model.JiraObjectSchema
+	startAt := 0
+	for {
+		query := url.Values{}
+		query.Set("startAt", strconv.Itoa(startAt))
+		query.Set("maxResults", strconv.Itoa(defaultJiraPageSize))
+
+		var page struct {
+			Total         int                `json:"total"`
+			IsLast        bool               `json:"isLast"`
+			Values        []jiraObjectSchema `json:"values"`
+			ObjectSchemas []jiraObjectSchema `json:"objectschemas"` // Data Center
+		}
+		if err := c.doJSON(ctx, http.MethodGet, "/objectschema/list?"+query.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("jira assets schema list failed: %w", err)
+		}
+
+		values := append(page.Values, page.ObjectSchemas...)
+		for _, s := range values {
+			schemas = append(schemas, model.JiraObjectSchema{ID: string(s.ID), Key: s.ObjectSchemaKey, Name: s.Name})
+		}
+
+		startAt += len(values)
+		if page.IsLast || len(values) == 0 || page.Total == 0 || startAt >= page.Total {
+			break
+		}
+	}
+	return schemas, nil
+}
+
+// ListObjectTypes returns every object type in a schema.
+func (c *JiraAssetsClient) ListObjectTypes(ctx context.Context, schemaID string) ([]model.JiraObjectType, error) {
+	var raw []jiraObjectType
+	path := "/objectschema/" + url.PathEscape(schemaID) + "/objecttypes/flat"
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &raw); err != nil {
+		return nil, fmt.Errorf("jira assets object type list failed for schema %s: %w", schemaID, err)
+	}
+
+	types := make([]model.JiraObjectType, 0, len(raw))
+	for _, t := range raw {
+		types = append(types, t.toModel())
+	}
+	return types, nil
+}
+
+// GetObjectType returns a single object type by ID.
+func (c *JiraAssetsClient) GetObjectType(ctx context.Context, typeID string) (model.JiraObjectType, error) {
+	var raw jiraObjectType
+	if err := c.doJSON(ctx, http.MethodGet, "/objecttype/"+url.PathEscape(typeID), nil, &raw); err != nil {
+		return model.JiraObjectType{}, fmt.Errorf("jira assets object type %s lookup failed: %w", typeID, err)
+	}
+	return raw.toModel(), nil
+}
+
+// ListObjectTypeAttributes returns the attribute definitions of an object type.
+func (c *JiraAssetsClient) ListObjectTypeAttributes(ctx context.Context, typeID string) ([]model.JiraObjectTypeAttribute, error) {
+	var raw []struct {
+		ID          jiraID `json:"id"`
+		Name        string `json:"name"`
+		Label       bool   `json:"label"`
+		Type        int    `json:"type"`
+		Editable    bool   `json:"editable"`
+		DefaultType *struct {
+			Name string `json:"name"`
+		} `json:"defaultType"`
+	}
+	path := "/objecttype/" + url.PathEscape(typeID) + "/attributes"
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &raw); err != nil {
+		return nil, fmt.Errorf("jira assets attribute list failed for object type %s: %w", typeID, err)
+	}
+
+	attrs := make([]model.JiraObjectTypeAttribute, 0, len(raw))
+	for _, a := range raw {
+		typeName := jiraReferenceTypes[a.Type]
+		if a.Type == 0 && a.DefaultType != nil {
+			typeName = a.DefaultType.Name
+		}
+		attrs = append(attrs, model.JiraObjectTypeAttribute{
+			ID:       string(a.ID),
+			Name:     a.Name,
+			Type:     typeName,
+			Editable: a.Editable,
+			Label:    a.Label,
+		})
+	}
+	return attrs, nil
+}
+
+// ValidateMapping checks the client's mapping against the live schema. It resolves the
+// object type and fills in any attribute IDs or names that were left out, so that reads,
+// writes and lookups all work. It fails if anything in the mapping cannot be found.
+func (c *JiraAssetsClient) ValidateMapping(ctx context.Context) error {
+	m := c.Mapping
+
+	objectType, err := c.resolveObjectType(ctx, m)
+	if err != nil {
+		return err
+	}
+	m.ObjectTypeID = objectType.ID
+	m.ObjectType = objectType.Name
+	if m.ObjectSchemaID == "" {
+		m.ObjectSchemaID = objectType.ObjectSchemaID
+	}
+
+	attrs, err := c.ListObjectTypeAttributes(ctx, objectType.ID)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]JiraAttributeRef, len(m.Fields))
+	var problems []string
+	for _, field := range m.mappedFields() {
+		ref := m.Fields[field]
+		attr, found := findAttribute(attrs, ref)
+		switch {
+		case !found:
+			problems = append(problems, fmt.Sprintf("%s: no attribute matches id=%q name=%q", field, ref.ID, ref.Name))
+			continue
+		case ref.ID != "" && ref.Name != "" && !strings.EqualFold(attr.Name, ref.Name):
+			problems = append(problems, fmt.Sprintf("%s: attribute %s is named %q, not %q", field, ref.ID, attr.Name, ref.Name))
+			continue
+		case isDateField(field) && attr.Type != "Date" && attr.Type != "DateTime":
+			problems = append(problems, fmt.Sprintf("%s: attribute %q has type %s, expected Date or DateTime", field, attr.Name, attr.Type))
+			continue
+		case field == FieldPurchaseCost && attr.Type == "Integer":
+			problems = append(problems, fmt.Sprintf("%s: attribute %q has type Integer, expected Double or Text to hold cents", field, attr.Name))
+			continue
+		}
+
+		fields[field] = JiraAttributeRef{ID: attr.ID, Name: attr.Name}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Jira mapping does not match object type %q (%s):\n  %s",
+			objectType.Name, objectType.ID, strings.Join(problems, "\n  "))
+	}
+
+	m.Fields = fields
+	c.Mapping = m
+	return nil
+}
+
+// resolveObjectType finds the mapped object type by ID, or by name within the mapped schema.
+func (c *JiraAssetsClient) resolveObjectType(ctx context.Context, m JiraDeviceMapping) (model.JiraObjectType, error) {
+	if m.ObjectTypeID != "" {
+		return c.GetObjectType(ctx, m.ObjectTypeID)
+	}
+
+	schemaIDs := []string{m.ObjectSchemaID}
+	if m.ObjectSchemaID == "" {
+		schemas, err := c.ListObjectSchemas(ctx)
+		if err != nil {
+			return model.JiraObjectType{}, err
+		}
+		schemaIDs = schemaIDs[:0]
+		for _, s := range schemas {
+			schemaIDs = append(schemaIDs, s.ID)
+		}
+	}
+
+	var matches []model.JiraObjectType
+	for _, schemaID := range schemaIDs {
+		types, err := c.ListObjectTypes(ctx, schemaID)
+		if err != nil {
+			return model.JiraObjectType{}, err
+		}
+		for _, t := range types {
+			if strings.EqualFold(t.Name, m.ObjectType) {
+				matches = append(matches, t)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return model.JiraObjectType{}, fmt.Errorf("Jira object type %q not found", m.ObjectType)
+	case 1:
+		return matches[0], nil
+	default:
+		return model.JiraObjectType{}, fmt.Errorf("Jira object type %q exists in %d schemas; set objectSchemaId or objectTypeId", m.ObjectType, len(matches))
+	}
+}
+
+// findAttribute returns the attribute matching the ref's ID, or its name when no ID is set.
+func findAttribute(attrs []model.JiraObjectTypeAttribute, ref JiraAttributeRef) (model.JiraObjectTypeAttribute, bool) {
+	for _, attr := range attrs {
+		if ref.ID != "" && attr.ID == ref.ID {
+			return attr, true
+		}
+		if ref.ID == "" && strings.EqualFold(attr.Name, ref.Name) {
+			return attr, true
+		}
+	}
+	return model.JiraObjectTypeAttribute{}, false
+}
+
+// jiraObjectSchema is an object schema as returned by the Assets API.
+type jiraObjectSchema struct {
+	ID              jiraID `json:"id"`
+	Name            string `json:"name"`
+	ObjectSchemaKey string `json:"objectSchemaKey"`
+}
+
+// jiraObjectType is an object type as returned by the Assets API.
+type jiraObjectType struct {
+	ID             jiraID `json:"id"`
+	Name           string `json:"name"`
+	ObjectSchemaID jiraID `json:"objectSchemaId"`
+}
+
+func (t jiraObjectType) toModel() model.JiraObjectType {
+	return model.JiraObjectType{ID: string(t.ID), Name: t.Name, ObjectSchemaID: string(t.ObjectSchemaID)}
+}
@@ -0,0 +1,471 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+	"bdemetris/curator/pkg/store/storetest"
+)
+
+func TestJiraAssetsStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		fake := newFakeJiraAssets()
+		server := httptest.NewServer(fake.handler())
+		t.Cleanup(server.Close)
+
+		mapping := DefaultJiraDeviceMapping()
+		mapping.ObjectTypeID = fakeJiraObjectTypeID
+		mapping.Fields[FieldAssetTag] = JiraAttributeRef{Name: "Asset Tag"}
+		mapping.Fields[FieldStatus] = JiraAttributeRef{Name: "Lifecycle Status"}
+		mapping.Fields[FieldSerialNumber] = JiraAttributeRef{Name: "Serial Number"}
+		mapping.Fields[FieldVendor] = JiraAttributeRef{Name: "Vendor"}
+		mapping.Fields[FieldPurchaseDate] = JiraAttributeRef{Name: "Purchase Date"}
+		mapping.Fields[FieldPurchaseCost] = JiraAttributeRef{Name: "Purchase Cost"}
+		mapping.Fields[FieldWarrantyExpiration] = JiraAttributeRef{Name: "Warranty Expiration"}
+		mapping.Fields[model.AttributeField("carrier")] = JiraAttributeRef{Name: "Carrier"}
+		mapping.Fields[model.AttributeField("os_version")] = JiraAttributeRef{Name: "OS Version"}
+		mapping.Fields[FieldModifiedBy] = JiraAttributeRef{Name: "Modified By"}
+
+		s, err := NewJiraAssetsClient(context.Background(), server.URL, "bot@example.com", "token", mapping)
+		if err != nil {
+			t.Fatalf("NewJiraAssetsClient: %v", err)
+		}
+		return s
+	})
+}
+
+const (
+	fakeJiraSchemaID     = "1"
+	fakeJiraObjectTypeID = "10"
+	fakeJiraObjectType   = "Device"
+	fakeJiraTimeLayout   = "2006-01-02T15:04:05.000Z0700"
+)
+
+// fakeJiraAttributes are the attributes of the fake's device object type, with their type.
+var fakeJiraAttributes = []struct {
+	ID, Name, Type string
+}{
+	{"100", "Asset Tag", "Text"},
+	{"101", "Device Type", "Text"},
+	{"102", "Make", "Text"},
+	{"103", "Model", "Text"},
+	{"104", "Location", "Text"},
+	{"105", "Assigned To", "Text"},
+	{"106", "Assigned Date", "DateTime"},
+	{"107", "Due Date", "DateTime"},
+	{"108", "Lifecycle Status", "Text"},
+	{"109", "Serial Number", "Text"},
+	{"110", "Vendor", "Text"},
+	{"111", "Purchase Date", "DateTime"},
+	{"112", "Purchase Cost", "Double"},
+	{"113", "Warranty Expiration", "DateTime"},
+	{"114", "Carrier", "Text"},
+	{"115", "OS Version", "Text"},
+	{"116", "Modified By", "Text"},
+}
+
+// fakeJiraAssets is an in-memory stand-in for the parts of the Jira Assets REST API used
+// by JiraAssetsClient. It stores attribute values as the strings it was sent and keeps
+// object history the way Jira does.
+type fakeJiraAssets struct {
+	mu      sync.Mutex
+	nextID  int
+	objects map[string]*fakeJiraObject
+	last    time.Time
+}
+
+type fakeJiraObject struct {
+	ID      string
+	Key     string
+	Created string
+	Values  map[string]string // Attribute ID to value
+	History []map[string]any
+}
+
+func newFakeJiraAssets() *fakeJiraAssets {
+	return &fakeJiraAssets{nextID: 1, objects: make(map[string]*fakeJiraObject)}
+}
+
+func (f *fakeJiraAssets) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /objecttype/{id}", f.getObjectType)
+	mux.HandleFunc("GET /objecttype/{id}/attributes", f.getAttributes)
+	mux.HandleFunc("POST /object/aql", f.searchObjects)
+	mux.HandleFunc("POST /object/create", f.createObject)
+	mux.HandleFunc("PUT /object/{id}", f.updateObject)
+	mux.HandleFunc("DELETE /object/{id}", f.deleteObject)
+	mux.HandleFunc("GET /object/{id}/history", f.getHistory)
+	return mux
+}
+
+// now returns the time of a change. Changes made at the same millisecond are merged in
+// object history, so every change gets a later time than the one before it.
+func (f *fakeJiraAssets) now() string {
+	t := time.Now().UTC().Truncate(time.Millisecond)
+	if !t.After(f.last) {
+		t = f.last.Add(time.Millisecond)
+	}
+	f.last = t
+	return t.Format(fakeJiraTimeLayout)
+}
+
+func (f *fakeJiraAssets) getObjectType(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("id") != fakeJiraObjectTypeID {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, map[string]any{"id": fakeJiraObjectTypeID, "name": fakeJiraObjectType, "objectSchemaId": fakeJiraSchemaID})
+}
+
+func (f *fakeJiraAssets) getAttributes(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("id") != fakeJiraObjectTypeID {
+		http.NotFound(w, r)
+		return
+	}
+	var attrs []map[string]any
+	for _, a := range fakeJiraAttributes {
+		attrs = append(attrs, map[string]any{
+			"id": a.ID, "name": a.Name, "type": 0, "editable": true, "label": a.ID == "100",
+			"defaultType": map[string]any{"name": a.Type},
+		})
+	}
+	writeJSON(w, attrs)
+}
+
+func (f *fakeJiraAssets) searchObjects(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		QLQuery string `json:"qlQuery"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	clauses, err := parseFakeAQL(body.QLQuery)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+	maxResults, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var matched []*fakeJiraObject
+	for _, obj := range f.sortedObjects() {
+		if slices.IndexFunc(clauses, func(c fakeAQLClause) bool { return !c.matches(obj) }) < 0 {
+			matched = append(matched, obj)
+		}
+	}
+
+	page := matched[min(startAt, len(matched)):]
+	if maxResults > 0 && len(page) > maxResults {
+		page = page[:maxResults]
+	}
+
+	values := []map[string]any{}
+	for _, obj := range page {
+		var attrs []map[string]any
+		for id, value := range obj.Values {
+			attrs = append(attrs, map[string]any{
+				"objectTypeAttributeId": id,
+				"objectAttributeValues": []map[string]any{{"value": value, "displayValue": value}},
+			})
+		}
+		values = append(values, map[string]any{
+			"id":         obj.ID,
+			"objectKey":  obj.Key,
+			"label":      obj.Values["100"],
+			"objectType": map[string]any{"id": fakeJiraObjectTypeID, "name": fakeJiraObjectType, "objectSchemaId": fakeJiraSchemaID},
+			"created":    obj.Created,
+			"updated":    obj.Created,
+			"attributes": attrs,
+		})
+	}
+
+	var typeAttrs []map[string]any
+	for _, a := range fakeJiraAttributes {
+		typeAttrs = append(typeAttrs, map[string]any{"id": a.ID, "name": a.Name})
+	}
+	writeJSON(w, map[string]any{
+		"startAt":              startAt,
+		"maxResults":           maxResults,
+		"total":                len(matched),
+		"isLast":               startAt+len(page) >= len(matched),
+		"values":               values,
+		"objectTypeAttributes": typeAttrs,
+	})
+}
+
+func (f *fakeJiraAssets) createObject(w http.ResponseWriter, r *http.Request) {
+	var body jiraObjectIn
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ObjectTypeID != fakeJiraObjectTypeID {
+		http.Error(w, "invalid object", http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := strconv.Itoa(f.nextID)
+	f.nextID++
+	obj := &fakeJiraObject{ID: id, Key: "DEV-" + id, Created: f.now(), Values: make(map[string]string)}
+	for _, attr := range body.Attributes {
+		if len(attr.ObjectAttributeValues) > 0 {
+			obj.Values[attr.ObjectTypeAttributeID] = attr.ObjectAttributeValues[0].Value
+		}
+	}
+	obj.History = append(obj.History, fakeJiraHistoryEntry(len(obj.History), jiraHistoryCreated, obj.Created, "", "", ""))
+	f.objects[id] = obj
+
+	writeJSON(w, map[string]any{"id": id, "objectKey": obj.Key})
+}
+
+func (f *fakeJiraAssets) updateObject(w http.ResponseWriter, r *http.Request) {
+	var body jiraObjectIn
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.objects[r.PathValue("id")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	created := f.now()
+	for _, attr := range body.Attributes {
+		old := obj.Values[attr.ObjectTypeAttributeID]
+		value := ""
+		if len(attr.ObjectAttributeValues) > 0 {
+			value = attr.ObjectAttributeValues[0].Value
+		}
+		if value == old {
+			continue
+		}
+
+		if value == "" {
+			delete(obj.Values, attr.ObjectTypeAttributeID)
+		} else {
+			obj.Values[attr.ObjectTypeAttributeID] = value
+		}
+		obj.History = append(obj.History, fakeJiraHistoryEntry(len(obj.History), 2, created, fakeJiraAttributeName(attr.ObjectTypeAttributeID), old, value))
+	}
+
+	writeJSON(w, map[string]any{"id": obj.ID, "objectKey": obj.Key})
+}
+
+func (f *fakeJiraAssets) deleteObject(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.objects[r.PathValue("id")]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	delete(f.objects, r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeJiraAssets) getHistory(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	obj, ok := f.objects[r.PathValue("id")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	history := slices.Clone(obj.History)
+	if r.URL.Query().Get("asc") != "true" {
+		slices.Reverse(history)
+	}
+	writeJSON(w, history)
+}
+
+// sortedObjects returns every object in the order they were created. The caller must hold mu.
+func (f *fakeJiraAssets) sortedObjects() []*fakeJiraObject {
+	objects := make([]*fakeJiraObject, 0, len(f.objects))
+	for _, obj := range f.objects {
+		objects = append(objects, obj)
+	}
+	slices.SortFunc(objects, func(a, b *fakeJiraObject) int {
+		x, _ := strconv.Atoi(a.ID)
+		y, _ := strconv.Atoi(b.ID)
+		return x - y
+	})
+	return objects
+}
+
+func fakeJiraHistoryEntry(id, entryType int, created, attribute, oldValue, newValue string) map[string]any {
+	return map[string]any{
+		"id":                id,
+		"actor":             map[string]any{"displayName": "Curator Bot", "emailAddress": "bot@example.com"},
+		"affectedAttribute": attribute,
+		"oldValue":          oldValue,
+		"newValue":          newValue,
+		"type":              entryType,
+		"created":           created,
+	}
+}
+
+func fakeJiraAttributeName(id string) string {
+	for _, a := range fakeJiraAttributes {
+		if a.ID == id {
+			return a.Name
+		}
+	}
+	return id
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// fakeAQLClause is one condition of the AQL subset JiraAssetsClient sends: equality,
+// IN lists and emptiness checks joined by AND. Comparisons ignore case, as in Jira.
+type fakeAQLClause struct {
+	Attribute string
+	Op        string // "=", "IN", "IS EMPTY" or "IS NOT EMPTY"
+	Values    []string
+}
+
+func (c fakeAQLClause) matches(obj *fakeJiraObject) bool {
+	var value string
+	switch strings.ToLower(c.Attribute) {
+	case "objectschemaid":
+		value = fakeJiraSchemaID
+	case "objecttype":
+		value = fakeJiraObjectType
+	case "key":
+		value = obj.Key
+	default:
+		for _, a := range fakeJiraAttributes {
+			if strings.EqualFold(a.Name, c.Attribute) {
+				value = obj.Values[a.ID]
+			}
+		}
+	}
+
+	switch c.Op {
+	case "IS EMPTY":
+		return value == ""
+	case "IS NOT EMPTY":
+		return value != ""
+	default:
+		return slices.ContainsFunc(c.Values, func(v string) bool { return strings.EqualFold(v, value) })
+	}
+}
+
+// parseFakeAQL parses the AQL subset described by fakeAQLClause.
+func parseFakeAQL(query string) ([]fakeAQLClause, error) {
+	tokens, err := tokenizeFakeAQL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	next := func() string {
+		if len(tokens) == 0 {
+			return ""
+		}
+		token := tokens[0]
+		tokens = tokens[1:]
+		return token
+	}
+
+	var clauses []fakeAQLClause
+	for {
+		clause := fakeAQLClause{Attribute: next()}
+		switch op := strings.ToUpper(next()); op {
+		case "=":
+			clause.Op = op
+			clause.Values = []string{next()}
+		case "IN":
+			clause.Op = op
+			if next() != "(" {
+				return nil, fmt.Errorf("expected ( after IN in %q", query)
+			}
+			for token := next(); token != ")"; token = next() {
+				if token == "" {
+					return nil, fmt.Errorf("unterminated IN list in %q", query)
+				}
+				if token != "," {
+					clause.Values = append(clause.Values, token)
+				}
+			}
+		case "IS":
+			clause.Op = "IS EMPTY"
+			token := strings.ToUpper(next())
+			if token == "NOT" {
+				clause.Op = "IS NOT EMPTY"
+				token = strings.ToUpper(next())
+			}
+			if token != "EMPTY" {
+				return nil, fmt.Errorf("expected EMPTY in %q", query)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported operator %q in %q", op, query)
+		}
+		clauses = append(clauses, clause)
+
+		switch token := next(); strings.ToUpper(token) {
+		case "":
+			return clauses, nil
+		case "AND":
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", token, query)
+		}
+	}
+}
+
+// tokenizeFakeAQL splits an AQL query into words, quoted strings (unquoted), parentheses
+// and commas.
+func tokenizeFakeAQL(query string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(query); {
+		switch ch := query[i]; {
+		case ch == ' ':
+			i++
+		case ch == '(' || ch == ')' || ch == ',':
+			tokens = append(tokens, string(ch))
+			i++
+		case ch == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' && i+1 < len(query) {
+					i++
+				}
+				b.WriteByte(query[i])
+			}
+			if i >= len(query) {
+				return nil, fmt.Errorf("unterminated string in %q", query)
+			}
+			tokens = append(tokens, b.String())
+			i++
+		default:
+			start := i
+			for i < len(query) && !strings.ContainsRune(` (),"`, rune(query[i])) {
+				i++
+			}
+			tokens = append(tokens, query[start:i])
+		}
+	}
+	return tokens, nil
+}
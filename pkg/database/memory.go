@@ -0,0 +1,498 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+// MemoryClient is a concurrency-safe, in-process implementation of store.Store.
+// It is intended for local development and tests where running DynamoDB Local is overkill.
+type MemoryClient struct {
+	mu           sync.RWMutex
+	devices      map[string]model.Device
+	events       map[string][]model.DeviceEvent
+	reservations map[string][]model.Reservation // By AssetTag
+	waitlist     map[string]model.WaitlistEntry // By EntryID
+}
+
+var _ store.Store = (*MemoryClient)(nil)
+
+// NewMemoryStore returns an empty in-memory store. If seedFile is set, the store
+// is pre-populated from a JSON array of devices.
+func NewMemoryStore(seedFile string) (store.Store, error) {
+	c := &MemoryClient{
+		devices:      make(map[string]model.Device),
+		events:       make(map[string][]model.DeviceEvent),
+		reservations: make(map[string][]model.Reservation),
+		waitlist:     make(map[string]model.WaitlistEntry),
+	}
+
+	if seedFile == "" {
+		return c, nil
+	}
+
+	if err := c.loadSeedFile(seedFile); err != nil {
+		return nil, fmt.Errorf("failed to load seed file %s: %w", seedFile, err)
+	}
+	log.Printf("Loaded %d devices from seed file %s", len(c.devices), seedFile)
+
+	return c, nil
+}
+
+// loadSeedFile reads a JSON array of model.Device and stores each entry.
+func (c *MemoryClient) loadSeedFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var devices []model.Device
+	if err := json.Unmarshal(data, &devices); err != nil {
+		return fmt.Errorf("failed to parse devices: %w", err)
+	}
+
+	for _, d := range devices {
+		if d.AssetTag == "" {
+			return fmt.Errorf("seed device is missing AssetTag")
+		}
+		c.devices[d.AssetTag] = d.Clone()
+	}
+	return nil
+}
+
+// Close is implemented to satisfy the Store interface.
+func (c *MemoryClient) Close() error {
+	return nil
+}
+
+// PutDevice stores a Device, replacing any existing device with the same AssetTag.
+func (c *MemoryClient) PutDevice(ctx context.Context, device model.Device) error {
+	if device.AssetTag == "" {
+		return fmt.Errorf("device is missing AssetTag")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setDevice(ctx, device)
+	return nil
+}
+
+// BatchPutDevices stores every device under a single lock.
+func (c *MemoryClient) BatchPutDevices(ctx context.Context, devices []model.Device) error {
+	for _, device := range devices {
+		if device.AssetTag == "" {
+			return fmt.Errorf("device is missing AssetTag")
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, device := range devices {
+		c.setDevice(ctx, device)
+	}
+	return nil
+}
+
+// BatchGetDevices returns the stored devices among deviceIDs, in the order asked for.
+func (c *MemoryClient) BatchGetDevices(ctx context.Context, deviceIDs []string) ([]model.Device, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	devices := make([]model.Device, 0, len(deviceIDs))
+	for _, id := range uniqueIDs(deviceIDs) {
+		if device, ok := c.devices[id]; ok {
+			devices = append(devices, device.Clone())
+		}
+	}
+	return devices, nil
+}
+
+// setDevice stores device and records the change in its history. The caller must hold mu.
+func (c *MemoryClient) setDevice(ctx context.Context, device model.Device) {
+	var before *model.Device
+	if current, ok := c.devices[device.AssetTag]; ok {
+		current = current.Clone()
+		before = &current
+	}
+
+	device = device.Clone()
+	c.devices[device.AssetTag] = device
+
+	if store.ChangesDevice(before, device) {
+		c.events[device.AssetTag] = append(c.events[device.AssetTag], store.NewDeviceEvent(ctx, before, device.Clone()))
+	}
+}
+
+// GetDevice retrieves a Device by its AssetTag.
+func (c *MemoryClient) GetDevice(ctx context.Context, deviceID string) (model.Device, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	device, ok := c.devices[deviceID]
+	if !ok {
+		return model.Device{}, &store.NotFoundError{AssetTag: deviceID}
+	}
+	return device.Clone(), nil
+}
+
+// ListDevices returns every stored device ordered by AssetTag.
+func (c *MemoryClient) ListDevices(ctx context.Context) ([]model.Device, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	devices := make([]model.Device, 0, len(c.devices))
+	for _, d := range c.devices {
+		devices = append(devices, d.Clone())
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].AssetTag < devices[j].AssetTag })
+
+	return devices, nil
+}
+
+// IterateDevices calls fn for a snapshot of every device, ordered by AssetTag.
+// The lock is not held while fn runs, so fn may call back into the store.
+func (c *MemoryClient) IterateDevices(ctx context.Context, fn func(model.Device) error) error {
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateDevice applies the patch to an existing device. Only the fields the patch
+// sets or clears change.
+func (c *MemoryClient) UpdateDevice(ctx context.Context, deviceID string, patch store.DevicePatch) error {
+	if err := patch.Validate(); err != nil {
+		return fmt.Errorf("invalid update for device ID %s: %w", deviceID, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, ok := c.devices[deviceID]
+	if !ok {
+		return &store.NotFoundError{AssetTag: deviceID}
+	}
+
+	c.setDevice(ctx, patch.Apply(current.Clone()))
+	return nil
+}
+
+// CheckoutDevice assigns the device if its status allows a checkout, it is not held by
+// anyone but assignee, and no reservation by someone else overlaps the checkout.
+func (c *MemoryClient) CheckoutDevice(ctx context.Context, deviceID, assignee string, assignedAt, dueAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	device, ok := c.devices[deviceID]
+	if !ok {
+		return &store.NotFoundError{AssetTag: deviceID}
+	}
+	if err := store.CheckTransition(device, model.StatusCheckedOut); err != nil {
+		return err
+	}
+	if device.AssignedTo != "" && device.AssignedTo != assignee {
+		return &store.ConflictError{AssetTag: deviceID, AssignedTo: device.AssignedTo, DueDate: device.Clone().DueDate}
+	}
+	if err := store.CheckCheckout(deviceID, assignee, c.reservations[deviceID], assignedAt, dueAt); err != nil {
+		return err
+	}
+
+	device.Status = model.StatusCheckedOut
+	device.AssignedTo = assignee
+	device.AssignedDate = &assignedAt
+	device.DueDate = &dueAt
+	c.setDevice(ctx, device)
+	return nil
+}
+
+// ReturnDevice clears the assignment if the device is checked out to assignee.
+func (c *MemoryClient) ReturnDevice(ctx context.Context, deviceID, assignee string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	device, ok := c.devices[deviceID]
+	if !ok {
+		return &store.NotFoundError{AssetTag: deviceID}
+	}
+	if device.AssignedTo != assignee {
+		return &store.ConflictError{AssetTag: deviceID, AssignedTo: device.AssignedTo, DueDate: device.Clone().DueDate}
+	}
+
+	device.Status = model.StatusAvailable
+	device.AssignedTo = ""
+	device.AssignedDate = nil
+	device.DueDate = nil
+	c.setDevice(ctx, device)
+	return nil
+}
+
+// DeleteDevice removes the device and its reservations. Its history is kept.
+func (c *MemoryClient) DeleteDevice(ctx context.Context, deviceID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	device, ok := c.devices[deviceID]
+	if !ok {
+		return &store.NotFoundError{AssetTag: deviceID}
+	}
+
+	delete(c.devices, deviceID)
+	delete(c.reservations, deviceID)
+	c.events[deviceID] = append(c.events[deviceID], store.NewDeviceDeletedEvent(ctx, device.Clone()))
+	return nil
+}
+
+// SetDeviceStatus moves the device to status if its current status allows it, and
+// clears any assignment if status ends a checkout.
+func (c *MemoryClient) SetDeviceStatus(ctx context.Context, deviceID string, status model.DeviceStatus) error {
+	if err := store.ValidateStatus(status); err != nil {
+		return fmt.Errorf("invalid status for device ID %s: %w", deviceID, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	device, ok := c.devices[deviceID]
+	if !ok {
+		return &store.NotFoundError{AssetTag: deviceID}
+	}
+	if err := store.CheckTransition(device, status); err != nil {
+		return err
+	}
+
+	device.Status = status
+	if status.ClearsAssignment() {
+		device.AssignedTo = ""
+		device.AssignedDate = nil
+		device.DueDate = nil
+	}
+	c.setDevice(ctx, device)
+	return nil
+}
+
+// QueryDevices returns the devices matching filter, ordered by AssetTag.
+func (c *MemoryClient) QueryDevices(ctx context.Context, filter store.DeviceFilter) ([]model.Device, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var devices []model.Device
+	for _, d := range c.devices {
+		if filter.Matches(d) {
+			devices = append(devices, d.Clone())
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].AssetTag < devices[j].AssetTag })
+
+	return devices, nil
+}
+
+// CreateReservation stores the reservation if it fits around the device's checkout and
+// other reservations.
+func (c *MemoryClient) CreateReservation(ctx context.Context, r model.Reservation) error {
+	if err := store.ValidateReservation(r); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	device, ok := c.devices[r.AssetTag]
+	if !ok {
+		return &store.NotFoundError{AssetTag: r.AssetTag}
+	}
+	if err := store.CheckReservation(device, c.reservations[r.AssetTag], r); err != nil {
+		return err
+	}
+
+	c.reservations[r.AssetTag] = append(c.reservations[r.AssetTag], r)
+	return nil
+}
+
+// ListReservations returns the reservations matching filter.
+func (c *MemoryClient) ListReservations(ctx context.Context, filter store.ReservationFilter) ([]model.Reservation, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var reservations []model.Reservation
+	for _, byDevice := range c.reservations {
+		for _, r := range byDevice {
+			if filter.Matches(r) {
+				reservations = append(reservations, r)
+			}
+		}
+	}
+	store.SortReservations(reservations)
+	return reservations, nil
+}
+
+// CancelReservation removes the reservation.
+func (c *MemoryClient) CancelReservation(ctx context.Context, deviceID, reservationID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reservations := c.reservations[deviceID]
+	for i, r := range reservations {
+		if r.ReservationID == reservationID {
+			c.reservations[deviceID] = slices.Delete(slices.Clone(reservations), i, i+1)
+			return nil
+		}
+	}
+	return &store.ReservationNotFoundError{AssetTag: deviceID, ReservationID: reservationID}
+}
+
+// JoinWaitlist stores the entry.
+func (c *MemoryClient) JoinWaitlist(ctx context.Context, e model.WaitlistEntry) error {
+	if err := store.ValidateWaitlistEntry(e); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.waitlist[e.EntryID]; ok {
+		return &store.WaitlistConflictError{EntryID: e.EntryID}
+	}
+	c.waitlist[e.EntryID] = e
+	return nil
+}
+
+// ListWaitlist returns the entries matching filter.
+func (c *MemoryClient) ListWaitlist(ctx context.Context, filter store.WaitlistFilter) ([]model.WaitlistEntry, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var entries []model.WaitlistEntry
+	for _, e := range c.waitlist {
+		if filter.Matches(e) {
+			if e.Offer != nil {
+				offer := *e.Offer
+				e.Offer = &offer
+			}
+			entries = append(entries, e)
+		}
+	}
+	store.SortWaitlist(entries)
+	return entries, nil
+}
+
+// OfferWaitlistEntry records the hold unless the entry already has one.
+func (c *MemoryClient) OfferWaitlistEntry(ctx context.Context, entryID string, offer model.WaitlistOffer) error {
+	if err := store.ValidateWaitlistOffer(offer); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.waitlist[entryID]
+	if !ok {
+		return &store.WaitlistEntryNotFoundError{EntryID: entryID}
+	}
+	if e.Offer != nil {
+		existing := *e.Offer
+		return &store.WaitlistConflictError{EntryID: entryID, Offer: &existing}
+	}
+	e.Offer = &offer
+	c.waitlist[entryID] = e
+	return nil
+}
+
+// LeaveWaitlist removes the entry.
+func (c *MemoryClient) LeaveWaitlist(ctx context.Context, entryID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.waitlist[entryID]; !ok {
+		return &store.WaitlistEntryNotFoundError{EntryID: entryID}
+	}
+	delete(c.waitlist, entryID)
+	return nil
+}
+
+// ListDeviceEvents returns the recorded history of a device, oldest first.
+func (c *MemoryClient) ListDeviceEvents(ctx context.Context, deviceID string) ([]model.DeviceEvent, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	events := make([]model.DeviceEvent, 0, len(c.events[deviceID]))
+	for _, e := range c.events[deviceID] {
+		events = append(events, cloneEvent(e))
+	}
+	return events, nil
+}
+
+// IterateDeviceEvents calls fn for a snapshot of every recorded event, ordered by AssetTag
+// and oldest first. Like IterateDevices, it does not hold the lock while fn runs.
+func (c *MemoryClient) IterateDeviceEvents(ctx context.Context, fn func(model.DeviceEvent) error) error {
+	c.mu.RLock()
+	tags := make([]string, 0, len(c.events))
+	for tag := range c.events {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var events []model.DeviceEvent
+	for _, tag := range tags {
+		for _, e := range c.events[tag] {
+			events = append(events, cloneEvent(e))
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, e := range events {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloneEvent returns a copy of e that shares no memory with it.
+func cloneEvent(e model.DeviceEvent) model.DeviceEvent {
+	if e.Before != nil {
+		before := e.Before.Clone()
+		e.Before = &before
+	}
+	if e.After != nil {
+		after := e.After.Clone()
+		e.After = &after
+	}
+	return e
+}
+
+// uniqueIDs returns ids without duplicates, keeping the first occurrence of each.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"testing"
+
+	"bdemetris/curator/pkg/store"
+	"bdemetris/curator/pkg/store/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := NewMemoryStore("")
+		if err != nil {
+			t.Fatalf("NewMemoryStore: %v", err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,833 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver, registered as "sqlite"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+// SQLiteClient stores devices in a single SQLite database file.
+type SQLiteClient struct {
+	db *sql.DB
+}
+
+var _ store.Store = (*SQLiteClient)(nil)
+
+// sqliteMigrations are applied in order; the index plus one is the schema version.
+// Never edit a migration that has shipped, append a new one instead.
+var sqliteMigrations = []string{
+	// 1: devices table
+	`CREATE TABLE devices (
+		asset_tag     TEXT PRIMARY KEY,
+		device_type   TEXT NOT NULL DEFAULT '',
+		device_make   TEXT NOT NULL DEFAULT '',
+		device_model  TEXT NOT NULL DEFAULT '',
+		location      TEXT NOT NULL DEFAULT '',
+		assigned_to   TEXT NOT NULL DEFAULT '',
+		assigned_date DATETIME,
+		due_date      DATETIME
+	)`,
+
+	// 2: indexes for QueryDevices
+	`CREATE INDEX devices_assigned_to ON devices (assigned_to COLLATE NOCASE);
+	CREATE INDEX devices_due_date ON devices (due_date)`,
+
+	// 3: append-only device history
+	`CREATE TABLE device_events (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id     TEXT NOT NULL,
+		asset_tag    TEXT NOT NULL,
+		type         TEXT NOT NULL,
+		actor        TEXT NOT NULL DEFAULT '',
+		occurred_at  DATETIME NOT NULL,
+		before_state TEXT, -- JSON-encoded model.Device, NULL when the device was created
+		after_state  TEXT
+	);
+	CREATE INDEX device_events_asset_tag ON device_events (asset_tag, id);
+	CREATE TRIGGER device_events_no_update BEFORE UPDATE ON device_events
+	BEGIN SELECT RAISE(ABORT, 'device_events is append-only'); END;
+	CREATE TRIGGER device_events_no_delete BEFORE DELETE ON device_events
+	BEGIN SELECT RAISE(ABORT, 'device_events is append-only'); END`,
+
+	// 4: lifecycle status
+	`ALTER TABLE devices ADD COLUMN status TEXT NOT NULL DEFAULT '';
+	UPDATE devices SET status = CASE WHEN assigned_to != '' THEN 'checked-out' ELSE 'available' END;
+	CREATE INDEX devices_status ON devices (status)`,
+
+	// 5: procurement and warranty details
+	`ALTER TABLE devices ADD COLUMN serial_number TEXT NOT NULL DEFAULT '';
+	ALTER TABLE devices ADD COLUMN vendor TEXT NOT NULL DEFAULT '';
+	ALTER TABLE devices ADD COLUMN purchase_date DATETIME;
+	ALTER TABLE devices ADD COLUMN purchase_cost INTEGER NOT NULL DEFAULT 0; -- cents
+	ALTER TABLE devices ADD COLUMN warranty_expiration DATETIME;
+	CREATE INDEX devices_warranty_expiration ON devices (warranty_expiration)`,
+
+	// 6: custom attributes, a JSON object of model.Device.Attributes
+	`ALTER TABLE devices ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}'`,
+
+	// 7: reservations
+	`CREATE TABLE reservations (
+		reservation_id TEXT PRIMARY KEY,
+		asset_tag      TEXT NOT NULL,
+		reserved_by    TEXT NOT NULL,
+		start_time     DATETIME NOT NULL,
+		end_time       DATETIME NOT NULL, -- exclusive
+		created_at     DATETIME NOT NULL
+	);
+	CREATE INDEX reservations_asset_tag ON reservations (asset_tag, start_time);
+	CREATE INDEX reservations_reserved_by ON reservations (reserved_by COLLATE NOCASE)`,
+
+	// 8: waitlist; the offer columns are NULL until a device is held for the entry
+	`CREATE TABLE waitlist (
+		entry_id             TEXT PRIMARY KEY,
+		query                TEXT NOT NULL,
+		requester            TEXT NOT NULL,
+		slack_user_id        TEXT NOT NULL DEFAULT '',
+		joined_at            DATETIME NOT NULL,
+		offer_asset_tag      TEXT,
+		offer_reservation_id TEXT,
+		offered_at           DATETIME,
+		offer_expires_at     DATETIME
+	);
+	CREATE INDEX waitlist_joined_at ON waitlist (joined_at)`,
+
+	// 9: the write that recorded each event, see store.WithWriteID
+	`ALTER TABLE device_events ADD COLUMN write_id TEXT NOT NULL DEFAULT ''`,
+}
+
+// sqliteDeviceColumns maps model.Device field names to their column.
+var sqliteDeviceColumns = map[string]string{
+	FieldAssetTag:     "asset_tag",
+	FieldDeviceType:   "device_type",
+	FieldDeviceMake:   "device_make",
+	FieldDeviceModel:  "device_model",
+	FieldLocation:     "location",
+	FieldAssignedTo:   "assigned_to",
+	FieldAssignedDate: "assigned_date",
+	FieldDueDate:      "due_date",
+	FieldStatus:       "status",
+
+	FieldSerialNumber:       "serial_number",
+	FieldVendor:             "vendor",
+	FieldPurchaseDate:       "purchase_date",
+	FieldPurchaseCost:       "purchase_cost",
+	FieldWarrantyExpiration: "warranty_expiration",
+}
+
+const sqliteDeviceSelect = `SELECT asset_tag, device_type, device_make, device_model, location,
+	assigned_to, assigned_date, due_date, status,
+	serial_number, vendor, purchase_date, purchase_cost, warranty_expiration, attributes FROM devices`
+
+// NewSQLiteStore opens (or creates) the database file at path and migrates it to the latest schema.
+func NewSQLiteStore(ctx context.Context, path string) (store.Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("SQLite store requires a database path")
+	}
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// SQLite allows a single writer; serializing connections avoids SQLITE_BUSY under load.
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate SQLite database: %w", err)
+	}
+
+	return &SQLiteClient{db: db}, nil
+}
+
+// migrateSQLite applies every migration newer than the recorded schema version.
+func migrateSQLite(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at DATETIME NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(sqliteMigrations); i++ {
+		version := i + 1
+		log.Printf("Applying SQLite migration %d...", version)
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// Close releases the database file.
+func (c *SQLiteClient) Close() error {
+	return c.db.Close()
+}
+
+// PutDevice stores a Device, replacing any existing device with the same AssetTag.
+func (c *SQLiteClient) PutDevice(ctx context.Context, device model.Device) error {
+	if device.AssetTag == "" {
+		return fmt.Errorf("device is missing AssetTag")
+	}
+
+	return c.writeDevice(ctx, device.AssetTag, func(tx *sql.Tx, before *model.Device) error {
+		return replaceSQLiteDevice(ctx, tx, device)
+	})
+}
+
+// replaceSQLiteDevice inserts the device, replacing any existing row.
+func replaceSQLiteDevice(ctx context.Context, tx *sql.Tx, device model.Device) error {
+	attrs, err := sqliteAttributes(device.Attributes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO devices
+		(asset_tag, device_type, device_make, device_model, location, assigned_to, assigned_date, due_date, status,
+		serial_number, vendor, purchase_date, purchase_cost, warranty_expiration, attributes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		device.AssetTag, device.DeviceType, device.DeviceMake, device.DeviceModel, device.Location,
+		device.AssignedTo, sqliteTime(device.AssignedDate), sqliteTime(device.DueDate), device.Status,
+		device.SerialNumber, device.Vendor, sqliteTime(device.PurchaseDate), device.PurchaseCost, sqliteTime(device.WarrantyExpiration),
+		attrs,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite insert failed for ID %s: %w", device.AssetTag, err)
+	}
+	return nil
+}
+
+// BatchPutDevices stores every device in a single transaction, so either all of them
+// are written or none are.
+func (c *SQLiteClient) BatchPutDevices(ctx context.Context, devices []model.Device) error {
+	for _, device := range devices {
+		if device.AssetTag == "" {
+			return fmt.Errorf("device is missing AssetTag")
+		}
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, device := range devices {
+		err := writeSQLiteDevice(ctx, tx, device.AssetTag, func(tx *sql.Tx, before *model.Device) error {
+			return replaceSQLiteDevice(ctx, tx, device)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// sqliteBatchSize keeps IN lists well below SQLite's limit on bound parameters.
+const sqliteBatchSize = 500
+
+// BatchGetDevices reads the devices with one query per sqliteBatchSize asset tags.
+func (c *SQLiteClient) BatchGetDevices(ctx context.Context, deviceIDs []string) ([]model.Device, error) {
+	ids := uniqueIDs(deviceIDs)
+	found := make(map[string]model.Device, len(ids))
+
+	for start := 0; start < len(ids); start += sqliteBatchSize {
+		chunk := ids[start:min(start+sqliteBatchSize, len(ids))]
+
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+
+		rows, err := c.db.QueryContext(ctx, sqliteDeviceSelect+` WHERE asset_tag IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite query failed: %w", err)
+		}
+		for rows.Next() {
+			device, err := scanSQLiteDevice(rows)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan device: %w", err)
+			}
+			found[device.AssetTag] = device
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("sqlite query failed: %w", err)
+		}
+	}
+
+	devices := make([]model.Device, 0, len(found))
+	for _, id := range ids {
+		if device, ok := found[id]; ok {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+// GetDevice retrieves a Device by its AssetTag.
+func (c *SQLiteClient) GetDevice(ctx context.Context, deviceID string) (model.Device, error) {
+	return getSQLiteDevice(ctx, c.db, deviceID)
+}
+
+// sqliteQueryer is satisfied by both *sql.DB and *sql.Tx.
+type sqliteQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// getSQLiteDevice reads a device through q, which may be a transaction.
+func getSQLiteDevice(ctx context.Context, q sqliteQueryer, deviceID string) (model.Device, error) {
+	row := q.QueryRowContext(ctx, sqliteDeviceSelect+` WHERE asset_tag = ?`, deviceID)
+
+	device, err := scanSQLiteDevice(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Device{}, &store.NotFoundError{AssetTag: deviceID}
+	}
+	if err != nil {
+		return model.Device{}, fmt.Errorf("sqlite query failed for ID %s: %w", deviceID, err)
+	}
+	return device, nil
+}
+
+// writeDevice runs write in a transaction, passing it the device as it was (nil if it did
+// not exist), and appends a history event in the same transaction if the device changed.
+func (c *SQLiteClient) writeDevice(ctx context.Context, deviceID string, write func(tx *sql.Tx, before *model.Device) error) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := writeSQLiteDevice(ctx, tx, deviceID, write); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// writeSQLiteDevice is writeDevice within a transaction owned by the caller.
+func writeSQLiteDevice(ctx context.Context, tx *sql.Tx, deviceID string, write func(tx *sql.Tx, before *model.Device) error) error {
+	var before *model.Device
+	current, err := getSQLiteDevice(ctx, tx, deviceID)
+	if err == nil {
+		before = &current
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	if err := write(tx, before); err != nil {
+		return err
+	}
+
+	after, err := getSQLiteDevice(ctx, tx, deviceID)
+	if err != nil {
+		return err
+	}
+	if store.ChangesDevice(before, after) {
+		return insertSQLiteEvent(ctx, tx, store.NewDeviceEvent(ctx, before, after))
+	}
+	return nil
+}
+
+// insertSQLiteEvent appends an event to device_events.
+func insertSQLiteEvent(ctx context.Context, tx *sql.Tx, event model.DeviceEvent) error {
+	before, err := sqliteJSON(event.Before)
+	if err != nil {
+		return err
+	}
+	after, err := sqliteJSON(event.After)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO device_events
+		(event_id, asset_tag, type, actor, occurred_at, before_state, after_state, write_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.EventID, event.AssetTag, string(event.Type), event.Actor, event.Timestamp.UTC(), before, after, event.WriteID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite insert failed for event on ID %s: %w", event.AssetTag, err)
+	}
+	return nil
+}
+
+// sqliteJSON encodes an optional device snapshot as a nullable JSON column value.
+func sqliteJSON(device *model.Device) (sql.NullString, error) {
+	if device == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(device)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal device snapshot: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// sqliteEventSelect selects the columns read by scanSQLiteEvent.
+const sqliteEventSelect = `SELECT id, event_id, asset_tag, type, actor, occurred_at, before_state, after_state, write_id FROM device_events`
+
+// ListDeviceEvents returns the recorded history of a device, oldest first.
+func (c *SQLiteClient) ListDeviceEvents(ctx context.Context, deviceID string) ([]model.DeviceEvent, error) {
+	rows, err := c.db.QueryContext(ctx, sqliteEventSelect+` WHERE asset_tag = ? ORDER BY id`, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var events []model.DeviceEvent
+	for rows.Next() {
+		_, e, err := scanSQLiteEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// IterateDeviceEvents walks the event log in insertion order, a page at a time like
+// IterateDevices, so fn may use the store.
+func (c *SQLiteClient) IterateDeviceEvents(ctx context.Context, fn func(model.DeviceEvent) error) error {
+	var after int64
+	for {
+		page, last, err := c.listEventPage(ctx, after, sqliteIteratePageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range page {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < sqliteIteratePageSize {
+			return nil
+		}
+		after = last
+	}
+}
+
+// listEventPage returns up to limit events recorded after the row with the given ID,
+// and the row ID of the last one.
+func (c *SQLiteClient) listEventPage(ctx context.Context, after int64, limit int) ([]model.DeviceEvent, int64, error) {
+	rows, err := c.db.QueryContext(ctx, sqliteEventSelect+` WHERE id > ? ORDER BY id LIMIT ?`, after, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var events []model.DeviceEvent
+	last := after
+	for rows.Next() {
+		id, e, err := scanSQLiteEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+		last = id
+	}
+	return events, last, rows.Err()
+}
+
+// scanSQLiteEvent reads an event row selected with sqliteEventSelect, returning its row ID.
+func scanSQLiteEvent(rows *sql.Rows) (int64, model.DeviceEvent, error) {
+	var id int64
+	var e model.DeviceEvent
+	var before, after sql.NullString
+	if err := rows.Scan(&id, &e.EventID, &e.AssetTag, &e.Type, &e.Actor, &e.Timestamp, &before, &after, &e.WriteID); err != nil {
+		return 0, e, fmt.Errorf("failed to scan event: %w", err)
+	}
+	if before.Valid {
+		if err := json.Unmarshal([]byte(before.String), &e.Before); err != nil {
+			return 0, e, fmt.Errorf("failed to decode event %s: %w", e.EventID, err)
+		}
+	}
+	if after.Valid {
+		if err := json.Unmarshal([]byte(after.String), &e.After); err != nil {
+			return 0, e, fmt.Errorf("failed to decode event %s: %w", e.EventID, err)
+		}
+	}
+	return id, e, nil
+}
+
+// sqliteIteratePageSize is the number of rows read per page by IterateDevices and
+// IterateDeviceEvents.
+const sqliteIteratePageSize = 200
+
+// ListDevices returns every stored device ordered by AssetTag.
+func (c *SQLiteClient) ListDevices(ctx context.Context) ([]model.Device, error) {
+	var devices []model.Device
+	err := c.IterateDevices(ctx, func(device model.Device) error {
+		devices = append(devices, device)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// IterateDevices walks the table in AssetTag order using keyset pagination. Each page is
+// read and its rows closed before fn runs, so fn may use the store (which has a single connection).
+func (c *SQLiteClient) IterateDevices(ctx context.Context, fn func(model.Device) error) error {
+	after := ""
+	for {
+		page, err := c.listDevicePage(ctx, after, sqliteIteratePageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, device := range page {
+			if err := fn(device); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < sqliteIteratePageSize {
+			return nil
+		}
+		after = page[len(page)-1].AssetTag
+	}
+}
+
+// listDevicePage returns up to limit devices whose AssetTag sorts after the given tag.
+func (c *SQLiteClient) listDevicePage(ctx context.Context, after string, limit int) ([]model.Device, error) {
+	rows, err := c.db.QueryContext(ctx, sqliteDeviceSelect+` WHERE asset_tag > ? ORDER BY asset_tag LIMIT ?`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []model.Device
+	for rows.Next() {
+		device, err := scanSQLiteDevice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+// UpdateDevice sets or clears only the fields of an existing device that the patch changes.
+func (c *SQLiteClient) UpdateDevice(ctx context.Context, deviceID string, patch store.DevicePatch) error {
+	if err := patch.Validate(); err != nil {
+		return fmt.Errorf("invalid update for device ID %s: %w", deviceID, err)
+	}
+
+	assignments := []string{}
+	args := []interface{}{}
+
+	for _, field := range patch.Fields() {
+		// Custom attributes share one JSON column, rewritten below once the device is read.
+		if _, ok := model.ParseAttributeField(field.Name); ok {
+			continue
+		}
+
+		column, ok := sqliteDeviceColumns[field.Name]
+		if !ok {
+			return fmt.Errorf("cannot update unknown field %s for device ID %s", field.Name, deviceID)
+		}
+
+		arg, err := sqliteValue(field.Value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", field.Name, err)
+		}
+
+		assignments = append(assignments, column+" = ?")
+		args = append(args, arg)
+	}
+
+	return c.writeDevice(ctx, deviceID, func(tx *sql.Tx, before *model.Device) error {
+		if before == nil {
+			return &store.NotFoundError{AssetTag: deviceID}
+		}
+
+		assignments, args := assignments, args
+		if len(patch.Attributes) > 0 {
+			attrs, err := sqliteAttributes(patch.Apply(*before).Attributes)
+			if err != nil {
+				return err
+			}
+			assignments = append(assignments, "attributes = ?")
+			args = append(args, attrs)
+		}
+
+		query := fmt.Sprintf(`UPDATE devices SET %s WHERE asset_tag = ?`, strings.Join(assignments, ", "))
+		args = append(args, deviceID)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("sqlite update failed for ID %s: %w", deviceID, err)
+		}
+		return nil
+	})
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSQLiteDevice reads one device row selected with sqliteDeviceSelect.
+func scanSQLiteDevice(row rowScanner) (model.Device, error) {
+	var d model.Device
+	var assigned, due, purchased, warranty sql.NullTime
+	var attrs string
+	if err := row.Scan(&d.AssetTag, &d.DeviceType, &d.DeviceMake, &d.DeviceModel, &d.Location,
+		&d.AssignedTo, &assigned, &due, &d.Status,
+		&d.SerialNumber, &d.Vendor, &purchased, &d.PurchaseCost, &warranty, &attrs); err != nil {
+		return model.Device{}, err
+	}
+	if err := json.Unmarshal([]byte(attrs), &d.Attributes); err != nil {
+		return model.Device{}, fmt.Errorf("invalid attributes for ID %s: %w", d.AssetTag, err)
+	}
+	if len(d.Attributes) == 0 {
+		d.Attributes = nil
+	}
+	d.AssignedDate = sqliteTimePtr(assigned)
+	d.DueDate = sqliteTimePtr(due)
+	d.PurchaseDate = sqliteTimePtr(purchased)
+	d.WarrantyExpiration = sqliteTimePtr(warranty)
+	return d, nil
+}
+
+// sqliteAttributes encodes custom attributes for the attributes column.
+func sqliteAttributes(attrs map[string]string) (string, error) {
+	if len(attrs) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	return string(data), nil
+}
+
+// sqliteTime converts an optional timestamp to a nullable UTC column value.
+func sqliteTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// sqliteTimePtr converts a nullable column value back to an optional timestamp.
+func sqliteTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// sqliteValue converts a store.PatchedField value into a column value.
+func sqliteValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string, int64:
+		return v, nil
+	case *time.Time:
+		return sqliteTime(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// CheckoutDevice assigns the device if its status allows a checkout, it is not held by
+// anyone but assignee, and no reservation by someone else overlaps the checkout. The
+// checks and the write share a transaction, so only one of several concurrent checkouts
+// can succeed, and none can overlap a reservation made meanwhile.
+func (c *SQLiteClient) CheckoutDevice(ctx context.Context, deviceID, assignee string, assignedAt, dueAt time.Time) error {
+	return c.writeDevice(ctx, deviceID, func(tx *sql.Tx, before *model.Device) error {
+		if before == nil {
+			return &store.NotFoundError{AssetTag: deviceID}
+		}
+		if err := store.CheckTransition(*before, model.StatusCheckedOut); err != nil {
+			return err
+		}
+		if before.AssignedTo != "" && before.AssignedTo != assignee {
+			return &store.ConflictError{AssetTag: deviceID, AssignedTo: before.AssignedTo, DueDate: before.DueDate}
+		}
+		reservations, err := querySQLiteReservations(ctx, tx, sqliteReservationSelect+` WHERE asset_tag = ?`, deviceID)
+		if err != nil {
+			return err
+		}
+		if err := store.CheckCheckout(deviceID, assignee, reservations, assignedAt, dueAt); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE devices SET status = ?, assigned_to = ?, assigned_date = ?, due_date = ? WHERE asset_tag = ?`,
+			model.StatusCheckedOut, assignee, sqliteTime(&assignedAt), sqliteTime(&dueAt), deviceID,
+		)
+		if err != nil {
+			return fmt.Errorf("sqlite checkout failed for ID %s: %w", deviceID, err)
+		}
+		return nil
+	})
+}
+
+// ReturnDevice clears the assignment if the device is checked out to assignee.
+func (c *SQLiteClient) ReturnDevice(ctx context.Context, deviceID, assignee string) error {
+	return c.writeDevice(ctx, deviceID, func(tx *sql.Tx, before *model.Device) error {
+		if before == nil {
+			return &store.NotFoundError{AssetTag: deviceID}
+		}
+		if before.AssignedTo != assignee {
+			return &store.ConflictError{AssetTag: deviceID, AssignedTo: before.AssignedTo, DueDate: before.DueDate}
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE devices SET status = ?, assigned_to = '', assigned_date = NULL, due_date = NULL WHERE asset_tag = ?`,
+			model.StatusAvailable, deviceID,
+		)
+		if err != nil {
+			return fmt.Errorf("sqlite return failed for ID %s: %w", deviceID, err)
+		}
+		return nil
+	})
+}
+
+// DeleteDevice removes the device row and its reservations, and appends a delete event,
+// in the same transaction.
+func (c *SQLiteClient) DeleteDevice(ctx context.Context, deviceID string) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getSQLiteDevice(ctx, tx, deviceID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM devices WHERE asset_tag = ?`, deviceID); err != nil {
+		return fmt.Errorf("sqlite delete failed for ID %s: %w", deviceID, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM reservations WHERE asset_tag = ?`, deviceID); err != nil {
+		return fmt.Errorf("sqlite delete failed for reservations of ID %s: %w", deviceID, err)
+	}
+	if err := insertSQLiteEvent(ctx, tx, store.NewDeviceDeletedEvent(ctx, before)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetDeviceStatus moves the device to status if its current status allows it, and
+// clears any assignment if status ends a checkout, checking and writing in one transaction.
+func (c *SQLiteClient) SetDeviceStatus(ctx context.Context, deviceID string, status model.DeviceStatus) error {
+	if err := store.ValidateStatus(status); err != nil {
+		return fmt.Errorf("invalid status for device ID %s: %w", deviceID, err)
+	}
+
+	return c.writeDevice(ctx, deviceID, func(tx *sql.Tx, before *model.Device) error {
+		if before == nil {
+			return &store.NotFoundError{AssetTag: deviceID}
+		}
+		if err := store.CheckTransition(*before, status); err != nil {
+			return err
+		}
+
+		query := `UPDATE devices SET status = ? WHERE asset_tag = ?`
+		if status.ClearsAssignment() {
+			query = `UPDATE devices SET status = ?, assigned_to = '', assigned_date = NULL, due_date = NULL WHERE asset_tag = ?`
+		}
+		_, err := tx.ExecContext(ctx, query, status, deviceID)
+		if err != nil {
+			return fmt.Errorf("sqlite status update failed for ID %s: %w", deviceID, err)
+		}
+		return nil
+	})
+}
+
+// QueryDevices translates the filter into a WHERE clause that can use the assigned_to,
+// due_date, status and warranty_expiration indexes, then re-checks each row with filter.Matches.
+func (c *SQLiteClient) QueryDevices(ctx context.Context, filter store.DeviceFilter) ([]model.Device, error) {
+	var where []string
+	var args []interface{}
+
+	if filter.AssignedTo != "" {
+		where = append(where, "assigned_to = ? COLLATE NOCASE")
+		args = append(args, strings.TrimSpace(filter.AssignedTo))
+	}
+	if filter.DeviceType != "" {
+		where = append(where, "device_type = ? COLLATE NOCASE")
+		args = append(args, strings.TrimSpace(filter.DeviceType))
+	}
+	if filter.Location != "" {
+		where = append(where, "location = ? COLLATE NOCASE")
+		args = append(args, strings.TrimSpace(filter.Location))
+	}
+	switch filter.Availability {
+	case store.OnlyAvailable:
+		where = append(where, "assigned_to = ''")
+	case store.OnlyCheckedOut:
+		where = append(where, "assigned_to != ''")
+	}
+	if filter.DueBefore != nil {
+		where = append(where, "due_date IS NOT NULL")
+	}
+	if filter.WarrantyBefore != nil {
+		where = append(where, "warranty_expiration IS NOT NULL")
+	}
+	// Rows written without a status have an empty one, see model.Device.CurrentStatus.
+	if filter.Status != "" {
+		where = append(where, "status IN (?, '')")
+		args = append(args, filter.Status)
+	}
+	if !filter.IncludeRetired && filter.Status != model.StatusRetired {
+		where = append(where, "status != 'retired'")
+	}
+
+	query := sqliteDeviceSelect
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY asset_tag"
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []model.Device
+	for rows.Next() {
+		device, err := scanSQLiteDevice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		if filter.Matches(device) {
+			devices = append(devices, device)
+		}
+	}
+	return devices, rows.Err()
+}
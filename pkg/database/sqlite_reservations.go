@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+const sqliteReservationSelect = `SELECT reservation_id, asset_tag, reserved_by, start_time, end_time, created_at FROM reservations`
+
+// CreateReservation checks the device and its reservations and inserts the reservation
+// in one transaction, so overlapping reservations cannot both succeed.
+func (c *SQLiteClient) CreateReservation(ctx context.Context, r model.Reservation) error {
+	if err := store.ValidateReservation(r); err != nil {
+		return err
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	device, err := getSQLiteDevice(ctx, tx, r.AssetTag)
+	if err != nil {
+		return err
+	}
+	existing, err := querySQLiteReservations(ctx, tx, sqliteReservationSelect+` WHERE asset_tag = ?`, r.AssetTag)
+	if err != nil {
+		return err
+	}
+	if err := store.CheckReservation(device, existing, r); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO reservations
+		(reservation_id, asset_tag, reserved_by, start_time, end_time, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		r.ReservationID, r.AssetTag, r.ReservedBy, r.Start.UTC(), r.End.UTC(), r.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite insert failed for reservation of ID %s: %w", r.AssetTag, err)
+	}
+	return tx.Commit()
+}
+
+// ListReservations narrows the query by device and person, then re-checks each row with
+// filter.Matches.
+func (c *SQLiteClient) ListReservations(ctx context.Context, filter store.ReservationFilter) ([]model.Reservation, error) {
+	var where []string
+	var args []interface{}
+
+	if filter.AssetTag != "" {
+		where = append(where, "asset_tag = ?")
+		args = append(args, filter.AssetTag)
+	}
+	if filter.ReservedBy != "" {
+		where = append(where, "reserved_by = ? COLLATE NOCASE")
+		args = append(args, strings.TrimSpace(filter.ReservedBy))
+	}
+
+	query := sqliteReservationSelect
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	all, err := querySQLiteReservations(ctx, c.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservations []model.Reservation
+	for _, r := range all {
+		if filter.Matches(r) {
+			reservations = append(reservations, r)
+		}
+	}
+	store.SortReservations(reservations)
+	return reservations, nil
+}
+
+// CancelReservation deletes the reservation row.
+func (c *SQLiteClient) CancelReservation(ctx context.Context, deviceID, reservationID string) error {
+	res, err := c.db.ExecContext(ctx, `DELETE FROM reservations WHERE asset_tag = ? AND reservation_id = ?`, deviceID, reservationID)
+	if err != nil {
+		return fmt.Errorf("sqlite delete failed for reservation %s: %w", reservationID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &store.ReservationNotFoundError{AssetTag: deviceID, ReservationID: reservationID}
+	}
+	return nil
+}
+
+// querySQLiteReservations reads the reservations selected with sqliteReservationSelect.
+func querySQLiteReservations(ctx context.Context, q sqliteQueryer, query string, args ...interface{}) ([]model.Reservation, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var reservations []model.Reservation
+	for rows.Next() {
+		var r model.Reservation
+		if err := rows.Scan(&r.ReservationID, &r.AssetTag, &r.ReservedBy, &r.Start, &r.End, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reservation: %w", err)
+		}
+		reservations = append(reservations, r)
+	}
+	return reservations, rows.Err()
+}
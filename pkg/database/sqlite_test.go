@@ -0,0 +1,20 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"bdemetris/curator/pkg/store"
+	"bdemetris/curator/pkg/store/storetest"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := NewSQLiteStore(context.Background(), filepath.Join(t.TempDir(), "curator.db"))
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		return s
+	})
+}
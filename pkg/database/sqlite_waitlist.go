@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+const sqliteWaitlistSelect = `SELECT entry_id, query, requester, slack_user_id, joined_at,
+	offer_asset_tag, offer_reservation_id, offered_at, offer_expires_at FROM waitlist`
+
+// JoinWaitlist inserts the entry unless one with its ID exists.
+func (c *SQLiteClient) JoinWaitlist(ctx context.Context, e model.WaitlistEntry) error {
+	if err := store.ValidateWaitlistEntry(e); err != nil {
+		return err
+	}
+
+	res, err := c.db.ExecContext(ctx, `INSERT INTO waitlist (entry_id, query, requester, slack_user_id, joined_at)
+		VALUES (?, ?, ?, ?, ?) ON CONFLICT (entry_id) DO NOTHING`,
+		e.EntryID, e.Query, e.Requester, e.SlackUserID, e.JoinedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite insert failed for waitlist entry %s: %w", e.EntryID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &store.WaitlistConflictError{EntryID: e.EntryID}
+	}
+	return nil
+}
+
+// ListWaitlist narrows the query by person, then re-checks each row with filter.Matches.
+func (c *SQLiteClient) ListWaitlist(ctx context.Context, filter store.WaitlistFilter) ([]model.WaitlistEntry, error) {
+	var where []string
+	var args []interface{}
+
+	if filter.Requester != "" {
+		where = append(where, "requester = ? COLLATE NOCASE")
+		args = append(args, strings.TrimSpace(filter.Requester))
+	}
+	if filter.AssetTag != "" {
+		where = append(where, "offer_asset_tag = ?")
+		args = append(args, filter.AssetTag)
+	}
+
+	query := sqliteWaitlistSelect
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	all, err := querySQLiteWaitlist(ctx, c.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []model.WaitlistEntry
+	for _, e := range all {
+		if filter.Matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	store.SortWaitlist(entries)
+	return entries, nil
+}
+
+// OfferWaitlistEntry checks and sets the offer columns in one transaction, so a second
+// offer for the same entry loses.
+func (c *SQLiteClient) OfferWaitlistEntry(ctx context.Context, entryID string, offer model.WaitlistOffer) error {
+	if err := store.ValidateWaitlistOffer(offer); err != nil {
+		return err
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entries, err := querySQLiteWaitlist(ctx, tx, sqliteWaitlistSelect+` WHERE entry_id = ?`, entryID)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return &store.WaitlistEntryNotFoundError{EntryID: entryID}
+	}
+	if entries[0].Offer != nil {
+		return &store.WaitlistConflictError{EntryID: entryID, Offer: entries[0].Offer}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE waitlist
+		SET offer_asset_tag = ?, offer_reservation_id = ?, offered_at = ?, offer_expires_at = ?
+		WHERE entry_id = ?`,
+		offer.AssetTag, offer.ReservationID, offer.OfferedAt.UTC(), offer.ExpiresAt.UTC(), entryID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite update failed for waitlist entry %s: %w", entryID, err)
+	}
+	return tx.Commit()
+}
+
+// LeaveWaitlist deletes the entry row.
+func (c *SQLiteClient) LeaveWaitlist(ctx context.Context, entryID string) error {
+	res, err := c.db.ExecContext(ctx, `DELETE FROM waitlist WHERE entry_id = ?`, entryID)
+	if err != nil {
+		return fmt.Errorf("sqlite delete failed for waitlist entry %s: %w", entryID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &store.WaitlistEntryNotFoundError{EntryID: entryID}
+	}
+	return nil
+}
+
+// querySQLiteWaitlist reads the entries selected with sqliteWaitlistSelect.
+func querySQLiteWaitlist(ctx context.Context, q sqliteQueryer, query string, args ...interface{}) ([]model.WaitlistEntry, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []model.WaitlistEntry
+	for rows.Next() {
+		var e model.WaitlistEntry
+		var assetTag, reservationID sql.NullString
+		var offeredAt, expiresAt sql.NullTime
+		if err := rows.Scan(&e.EntryID, &e.Query, &e.Requester, &e.SlackUserID, &e.JoinedAt,
+			&assetTag, &reservationID, &offeredAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan waitlist entry: %w", err)
+		}
+		if assetTag.Valid {
+			e.Offer = &model.WaitlistOffer{
+				AssetTag:      assetTag.String,
+				ReservationID: reservationID.String,
+				OfferedAt:     offeredAt.Time,
+				ExpiresAt:     expiresAt.Time,
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -14,6 +14,48 @@ type JiraAsset struct {
 	Owner        string    // Derived from attributes (e.g., Assigned User)
 	Created      time.Time `json:"created"`
 	Updated      time.Time `json:"lastModified"`
+
+	Attributes []JiraAssetAttribute `json:"attributes"`
+}
+
+// JiraAssetAttribute is a single attribute value of a Jira Asset, flattened to a string.
+type JiraAssetAttribute struct {
+	ID    string `json:"objectTypeAttributeId"` // The object type attribute ID
+	Name  string `json:"name"`                  // The object type attribute name (e.g., "Location")
+	Value string `json:"value"`                 // The first value of the attribute
+}
+
+// Attribute returns the value of the attribute matching either the given ID or name.
+func (a JiraAsset) Attribute(idOrName string) (string, bool) {
+	for _, attr := range a.Attributes {
+		if attr.ID == idOrName || (attr.Name != "" && attr.Name == idOrName) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// JiraObjectSchema is a top-level container of object types in Jira Assets (e.g., "IT Assets").
+type JiraObjectSchema struct {
+	ID   string
+	Key  string
+	Name string
+}
+
+// JiraObjectType is a type of object within a schema (e.g., "Laptop").
+type JiraObjectType struct {
+	ID             string
+	Name           string
+	ObjectSchemaID string
+}
+
+// JiraObjectTypeAttribute is the definition of an attribute on an object type.
+type JiraObjectTypeAttribute struct {
+	ID       string
+	Name     string
+	Type     string // The value type, e.g. "Text", "Date", "DateTime", "User", "Object"
+	Editable bool
+	Label    bool // Whether this attribute is the object's label (usually "Name")
 }
 
 // AssetSearchOptions defines parameters for searching Jira Assets.
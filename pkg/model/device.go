@@ -1,15 +1,104 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Device is the public data model used accross the app
 type Device struct {
-	AssetTag     string     `dynamodbav:"AssetTag"`
-	DeviceType   string     `dynamodbav:"DeviceType"`
-	DeviceMake   string     `dynamodbav:"DeviceMake"`
-	DeviceModel  string     `dynamodbav:"DeviceModel"`
-	Location     string     `dynamodbav:"Location"`
-	AssignedTo   string     `dynamodbav:"AssignedTo"`
-	AssignedDate *time.Time `dynamodbav:"AssignedDate"`
-	DueDate      *time.Time `dynamodbav:"DueDate"`
+	AssetTag     string       `dynamodbav:"AssetTag"`
+	DeviceType   string       `dynamodbav:"DeviceType"`
+	DeviceMake   string       `dynamodbav:"DeviceMake"`
+	DeviceModel  string       `dynamodbav:"DeviceModel"`
+	Location     string       `dynamodbav:"Location"`
+	AssignedTo   string       `dynamodbav:"AssignedTo,omitempty"`
+	AssignedDate *time.Time   `dynamodbav:"AssignedDate,omitempty"`
+	DueDate      *time.Time   `dynamodbav:"DueDate,omitempty"`
+	Status       DeviceStatus `dynamodbav:"Status,omitempty"` // Empty for devices stored before statuses existed, see CurrentStatus
+
+	// Procurement details, all optional.
+	SerialNumber       string     `dynamodbav:"SerialNumber,omitempty"`
+	Vendor             string     `dynamodbav:"Vendor,omitempty"` // Who the device was bought from
+	PurchaseDate       *time.Time `dynamodbav:"PurchaseDate,omitempty"`
+	PurchaseCost       int64      `dynamodbav:"PurchaseCost,omitempty"` // In cents, see FormatCost
+	WarrantyExpiration *time.Time `dynamodbav:"WarrantyExpiration,omitempty"`
+
+	// Attributes holds the custom attributes defined for the device's type by a
+	// SchemaRegistry, keyed by AttributeDef.Key.
+	Attributes map[string]string `dynamodbav:"Attributes,omitempty"`
+}
+
+// CurrentStatus returns the device's lifecycle status. Devices stored before statuses
+// existed have none, and are checked out if they are assigned to someone.
+func (d Device) CurrentStatus() DeviceStatus {
+	switch {
+	case d.Status != "":
+		return d.Status
+	case d.AssignedTo != "":
+		return StatusCheckedOut
+	default:
+		return StatusAvailable
+	}
+}
+
+// Clone returns a copy of the device that shares no pointers or maps with it.
+func (d Device) Clone() Device {
+	if d.AssignedDate != nil {
+		t := *d.AssignedDate
+		d.AssignedDate = &t
+	}
+	if d.DueDate != nil {
+		t := *d.DueDate
+		d.DueDate = &t
+	}
+	if d.PurchaseDate != nil {
+		t := *d.PurchaseDate
+		d.PurchaseDate = &t
+	}
+	if d.WarrantyExpiration != nil {
+		t := *d.WarrantyExpiration
+		d.WarrantyExpiration = &t
+	}
+	if d.Attributes != nil {
+		d.Attributes = maps.Clone(d.Attributes)
+	}
+	return d
+}
+
+// FormatCost formats an amount in cents as a decimal, e.g. 129900 as "1299.00". Zero,
+// meaning the cost is unknown, formats as "".
+func FormatCost(cents int64) string {
+	if cents == 0 {
+		return ""
+	}
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// ParseCost parses a decimal amount as written in a spreadsheet or invoice, such as
+// "1299", "1,299.00" or "$1299.5", into cents. An empty string is zero.
+func ParseCost(s string) (int64, error) {
+	cleaned := strings.TrimSpace(strings.NewReplacer(",", "", "$", "", "€", "", "£", "").Replace(s))
+	if cleaned == "" {
+		return 0, nil
+	}
+
+	whole, fraction, _ := strings.Cut(cleaned, ".")
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("cost %q has more than two decimal places", s)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	cents, err := strconv.ParseUint(whole+fraction, 10, 63)
+	if err != nil || whole == "" && fraction == "00" {
+		return 0, fmt.Errorf("invalid cost %q", s)
+	}
+	return int64(cents), nil
 }
@@ -0,0 +1,44 @@
+package model
+
+import "testing"
+
+func TestParseCost(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "1299", want: 129900},
+		{value: "1299.00", want: 129900},
+		{value: "1,299.00", want: 129900},
+		{value: "$1299.5", want: 129950},
+		{value: " €49.99 ", want: 4999},
+		{value: ".5", want: 50},
+		{value: "0", want: 0},
+		{value: "", want: 0},
+		{value: "12.345", wantErr: true},
+		{value: ".", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "twelve", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCost(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCost(%q) = %d, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCost(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatCost(t *testing.T) {
+	for cents, want := range map[int64]string{0: "", 5: "0.05", 129900: "1299.00", 129950: "1299.50", -250: "-2.50"} {
+		if got := FormatCost(cents); got != want {
+			t.Errorf("FormatCost(%d) = %q, want %q", cents, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// DeviceEventType classifies an entry in a device's history.
+type DeviceEventType string
+
+const (
+	DeviceCreated    DeviceEventType = "create"
+	DeviceCheckedOut DeviceEventType = "checkout"
+	DeviceReturned   DeviceEventType = "return"
+	DeviceRenewed    DeviceEventType = "renew"
+	DeviceEdited     DeviceEventType = "edit"
+	DeviceRetired    DeviceEventType = "retire"
+	DeviceStatusSet  DeviceEventType = "status"
+	DeviceDeleted    DeviceEventType = "delete"
+)
+
+// DeviceEvent is one entry in the append-only history of a device. Before and After
+// are full snapshots of the device around the change.
+type DeviceEvent struct {
+	AssetTag  string          `dynamodbav:"AssetTag"`
+	EventID   string          `dynamodbav:"EventID"` // Unique per device, sorts in the order events were recorded
+	Type      DeviceEventType `dynamodbav:"Type"`
+	Actor     string          `dynamodbav:"Actor"` // Who made the change, usually an email address
+	Timestamp time.Time       `dynamodbav:"Timestamp"`
+	Before    *Device         `dynamodbav:"Before,omitempty"`  // nil when the device was created
+	After     *Device         `dynamodbav:"After,omitempty"`   // nil when the device was deleted
+	WriteID   string          `dynamodbav:"WriteID,omitempty"` // The write that recorded the event, see store.WithWriteID
+}
+
+// FieldChange is a single field that differs between two snapshots of a device.
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Changes lists the fields that differ between the event's Before and After snapshots.
+func (e DeviceEvent) Changes() []FieldChange {
+	var before, after Device
+	if e.Before != nil {
+		before = *e.Before
+	}
+	if e.After != nil {
+		after = *e.After
+	}
+	return DiffDevices(before, after)
+}
+
+// ClassifyDeviceChange returns the event type describing a change from before to
+// after. before is nil for a newly created device.
+func ClassifyDeviceChange(before *Device, after Device) DeviceEventType {
+	switch {
+	case before == nil:
+		return DeviceCreated
+	case after.CurrentStatus() == StatusRetired && before.CurrentStatus() != StatusRetired:
+		return DeviceRetired
+	case after.AssignedTo == "" && before.AssignedTo != "":
+		return DeviceReturned
+	case after.AssignedTo != "" && after.AssignedTo != before.AssignedTo:
+		return DeviceCheckedOut
+	case after.AssignedTo != "" && !sameTime(before.DueDate, after.DueDate):
+		return DeviceRenewed
+	case after.CurrentStatus() != before.CurrentStatus():
+		return DeviceStatusSet
+	default:
+		return DeviceEdited
+	}
+}
+
+// DiffDevices lists the fields whose values differ between two devices.
+func DiffDevices(before, after Device) []FieldChange {
+	var changes []FieldChange
+	text := func(field, b, a string) {
+		if b != a {
+			changes = append(changes, FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+	date := func(field string, b, a *time.Time) {
+		if !sameTime(b, a) {
+			changes = append(changes, FieldChange{Field: field, Before: formatTime(b), After: formatTime(a)})
+		}
+	}
+
+	text("AssetTag", before.AssetTag, after.AssetTag)
+	text("DeviceType", before.DeviceType, after.DeviceType)
+	text("DeviceMake", before.DeviceMake, after.DeviceMake)
+	text("DeviceModel", before.DeviceModel, after.DeviceModel)
+	text("Location", before.Location, after.Location)
+	text("AssignedTo", before.AssignedTo, after.AssignedTo)
+	date("AssignedDate", before.AssignedDate, after.AssignedDate)
+	date("DueDate", before.DueDate, after.DueDate)
+	text("Status", statusText(before), statusText(after))
+	text("SerialNumber", before.SerialNumber, after.SerialNumber)
+	text("Vendor", before.Vendor, after.Vendor)
+	date("PurchaseDate", before.PurchaseDate, after.PurchaseDate)
+	text("PurchaseCost", FormatCost(before.PurchaseCost), FormatCost(after.PurchaseCost))
+	date("WarrantyExpiration", before.WarrantyExpiration, after.WarrantyExpiration)
+
+	keys := make([]string, 0, len(before.Attributes)+len(after.Attributes))
+	for key := range before.Attributes {
+		keys = append(keys, key)
+	}
+	for key := range after.Attributes {
+		if _, ok := before.Attributes[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		text(AttributeField(key), before.Attributes[key], after.Attributes[key])
+	}
+	return changes
+}
+
+// sameTime reports whether two optional timestamps are both unset or the same instant.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// statusText is the status shown in a diff. The zero device, standing in for a device
+// that does not exist, has none.
+func statusText(d Device) string {
+	if d.AssetTag == "" {
+		return ""
+	}
+	return string(d.CurrentStatus())
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
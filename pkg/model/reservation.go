@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Reservation books a device for one user over a window of time, usually in the future.
+// Reservations are separate from checkouts: the user still checks the device out once
+// the window starts.
+type Reservation struct {
+	ReservationID string    `dynamodbav:"ReservationID"` // Short and unique, for users to type
+	AssetTag      string    `dynamodbav:"AssetTag"`
+	ReservedBy    string    `dynamodbav:"ReservedBy"` // Email address, like Device.AssignedTo
+	Start         time.Time `dynamodbav:"Start"`
+	End           time.Time `dynamodbav:"End"` // Exclusive
+	CreatedAt     time.Time `dynamodbav:"CreatedAt"`
+}
+
+// Overlaps reports whether the reservation shares any time with [start, end).
+func (r Reservation) Overlaps(start, end time.Time) bool {
+	return r.Start.Before(end) && start.Before(r.End)
+}
+
+// ActiveAt reports whether t falls within the reservation.
+func (r Reservation) ActiveAt(t time.Time) bool {
+	return !t.Before(r.Start) && t.Before(r.End)
+}
@@ -0,0 +1,276 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttributeType is the type of a custom device attribute. Values of every type are
+// stored as strings in Device.Attributes, in the canonical form NormalizeAttribute returns.
+type AttributeType string
+
+const (
+	AttributeText   AttributeType = "text"
+	AttributeNumber AttributeType = "number"
+	AttributeBool   AttributeType = "bool"
+	AttributeDate   AttributeType = "date" // Stored as YYYY-MM-DD
+	AttributeEnum   AttributeType = "enum" // One of AttributeDef.Values
+)
+
+// attributeKeyPattern restricts attribute keys to what users can type in a chat command.
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// attributeFieldPrefix turns an attribute key into a field name, see AttributeField.
+const attributeFieldPrefix = "Attributes."
+
+// AttributeField returns the field name of a custom attribute, e.g. "Attributes.carrier",
+// used wherever fixed Device fields are named, such as history diffs and store patches.
+func AttributeField(key string) string {
+	return attributeFieldPrefix + key
+}
+
+// ParseAttributeField returns the attribute key of a field name made by AttributeField.
+func ParseAttributeField(field string) (string, bool) {
+	return strings.CutPrefix(field, attributeFieldPrefix)
+}
+
+// AttributeDef defines one custom attribute of a device type.
+type AttributeDef struct {
+	Key      string        `json:"key"`             // Lower-case identifier, e.g. "os_version"
+	Label    string        `json:"label,omitempty"` // Shown to users, e.g. "OS Version"; defaults to Key
+	Type     AttributeType `json:"type"`
+	Values   []string      `json:"values,omitempty"` // The allowed values of an enum
+	Unit     string        `json:"unit,omitempty"`   // Shown after numbers, e.g. "in"
+	Required bool          `json:"required,omitempty"`
+}
+
+// DisplayName returns the label of the attribute, or its key if it has none.
+func (a AttributeDef) DisplayName() string {
+	if a.Label != "" {
+		return a.Label
+	}
+	return a.Key
+}
+
+// Format returns a stored value as shown to users, with its unit.
+func (a AttributeDef) Format(value string) string {
+	if a.Unit != "" && value != "" {
+		return value + " " + a.Unit
+	}
+	return value
+}
+
+// normalize checks a value against the attribute's type and returns its canonical form.
+func (a AttributeDef) normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("%s cannot be empty", a.Key)
+	}
+
+	switch a.Type {
+	case AttributeText:
+		return value, nil
+	case AttributeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a number, got %q", a.Key, value)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case AttributeBool:
+		b, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false, got %q", a.Key, value)
+		}
+		return strconv.FormatBool(b), nil
+	case AttributeDate:
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a date like 2024-03-01, got %q", a.Key, value)
+		}
+		return t.Format(time.DateOnly), nil
+	case AttributeEnum:
+		for _, allowed := range a.Values {
+			if strings.EqualFold(allowed, value) {
+				return allowed, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s, got %q", a.Key, strings.Join(a.Values, ", "), value)
+	default:
+		return "", fmt.Errorf("%s has unknown type %q", a.Key, a.Type)
+	}
+}
+
+// DeviceTypeSchema lists the custom attributes of one device type.
+type DeviceTypeSchema struct {
+	DeviceType string         `json:"deviceType"` // Matched case-insensitively
+	Attributes []AttributeDef `json:"attributes"`
+}
+
+// Attribute returns the definition of the attribute with the given key.
+func (s DeviceTypeSchema) Attribute(key string) (AttributeDef, bool) {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a, true
+		}
+	}
+	return AttributeDef{}, false
+}
+
+// SchemaRegistry holds the custom attribute schema of every device type that has one.
+// Devices of other types cannot have custom attributes.
+type SchemaRegistry struct {
+	schemas map[string]DeviceTypeSchema // By normalized device type
+}
+
+// NewSchemaRegistry checks the schemas and builds a registry from them.
+func NewSchemaRegistry(schemas ...DeviceTypeSchema) (*SchemaRegistry, error) {
+	r := &SchemaRegistry{schemas: make(map[string]DeviceTypeSchema, len(schemas))}
+	for _, s := range schemas {
+		deviceType := normalizeDeviceType(s.DeviceType)
+		if deviceType == "" {
+			return nil, fmt.Errorf("device type schema is missing deviceType")
+		}
+		if _, ok := r.schemas[deviceType]; ok {
+			return nil, fmt.Errorf("device type %q has more than one schema", s.DeviceType)
+		}
+
+		seen := make(map[string]bool, len(s.Attributes))
+		for _, a := range s.Attributes {
+			switch {
+			case !attributeKeyPattern.MatchString(a.Key):
+				return nil, fmt.Errorf("device type %q: attribute key %q must be lower-case letters, digits and underscores", s.DeviceType, a.Key)
+			case seen[a.Key]:
+				return nil, fmt.Errorf("device type %q: attribute %q is defined twice", s.DeviceType, a.Key)
+			case a.Type == AttributeEnum && len(a.Values) == 0:
+				return nil, fmt.Errorf("device type %q: enum attribute %q has no values", s.DeviceType, a.Key)
+			case !slices.Contains([]AttributeType{AttributeText, AttributeNumber, AttributeBool, AttributeDate, AttributeEnum}, a.Type):
+				return nil, fmt.Errorf("device type %q: attribute %q has unknown type %q", s.DeviceType, a.Key, a.Type)
+			}
+			seen[a.Key] = true
+		}
+		r.schemas[deviceType] = s
+	}
+	return r, nil
+}
+
+// DefaultSchemaRegistry returns the schemas used when none are configured.
+func DefaultSchemaRegistry() *SchemaRegistry {
+	r, err := NewSchemaRegistry(
+		DeviceTypeSchema{DeviceType: "Phone", Attributes: []AttributeDef{
+			{Key: "os_version", Label: "OS Version", Type: AttributeText},
+			{Key: "carrier", Label: "Carrier", Type: AttributeText},
+		}},
+		DeviceTypeSchema{DeviceType: "Monitor", Attributes: []AttributeDef{
+			{Key: "size", Label: "Size", Type: AttributeNumber, Unit: "in"},
+		}},
+		DeviceTypeSchema{DeviceType: "Dev Board", Attributes: []AttributeDef{
+			{Key: "firmware_revision", Label: "Firmware Revision", Type: AttributeText},
+		}},
+	)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// LoadSchemaRegistry reads the schemas from a JSON file holding a list of device type
+// schemas. An empty path returns the default registry.
+func LoadSchemaRegistry(path string) (*SchemaRegistry, error) {
+	if path == "" {
+		return DefaultSchemaRegistry(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device schema file: %w", err)
+	}
+
+	var schemas []DeviceTypeSchema
+	if err := json.Unmarshal(data, &schemas); err != nil {
+		return nil, fmt.Errorf("failed to parse device schema file %s: %w", path, err)
+	}
+
+	r, err := NewSchemaRegistry(schemas...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device schema file %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Schema returns the schema of a device type.
+func (r *SchemaRegistry) Schema(deviceType string) (DeviceTypeSchema, bool) {
+	s, ok := r.schemas[normalizeDeviceType(deviceType)]
+	return s, ok
+}
+
+// Schemas returns every schema, ordered by device type.
+func (r *SchemaRegistry) Schemas() []DeviceTypeSchema {
+	schemas := make([]DeviceTypeSchema, 0, len(r.schemas))
+	for _, s := range r.schemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].DeviceType < schemas[j].DeviceType })
+	return schemas
+}
+
+// NormalizeAttribute checks one attribute value against the schema of a device type
+// and returns its key and value in canonical form.
+func (r *SchemaRegistry) NormalizeAttribute(deviceType, key, value string) (string, string, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+
+	schema, ok := r.Schema(deviceType)
+	if !ok {
+		return "", "", fmt.Errorf("device type %q has no custom attributes", deviceType)
+	}
+	def, ok := schema.Attribute(key)
+	if !ok {
+		return "", "", fmt.Errorf("device type %q has no attribute %q", schema.DeviceType, key)
+	}
+
+	value, err := def.normalize(value)
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// NormalizeDevice checks a device's custom attributes against the schema of its type.
+// It returns a copy with canonical keys and values, or every problem it found.
+func (r *SchemaRegistry) NormalizeDevice(d Device) (Device, []string) {
+	var problems []string
+	attrs := make(map[string]string, len(d.Attributes))
+	for key, value := range d.Attributes {
+		key, value, err := r.NormalizeAttribute(d.DeviceType, key, value)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		attrs[key] = value
+	}
+
+	if schema, ok := r.Schema(d.DeviceType); ok {
+		for _, def := range schema.Attributes {
+			if _, ok := attrs[def.Key]; def.Required && !ok {
+				problems = append(problems, fmt.Sprintf("%s is required for %s devices", def.Key, schema.DeviceType))
+			}
+		}
+	}
+
+	sort.Strings(problems)
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+	d.Attributes = attrs
+	return d, problems
+}
+
+func normalizeDeviceType(deviceType string) string {
+	return strings.ToLower(strings.TrimSpace(deviceType))
+}
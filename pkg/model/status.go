@@ -0,0 +1,90 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DeviceStatus is where a device is in its lifecycle.
+type DeviceStatus string
+
+const (
+	StatusAvailable  DeviceStatus = "available"
+	StatusCheckedOut DeviceStatus = "checked-out"
+	StatusInRepair   DeviceStatus = "in-repair"
+	StatusLost       DeviceStatus = "lost"
+	StatusRetired    DeviceStatus = "retired" // Kept for history, but can never be used again
+)
+
+// DeviceStatuses lists every lifecycle status.
+var DeviceStatuses = []DeviceStatus{
+	StatusAvailable,
+	StatusCheckedOut,
+	StatusInRepair,
+	StatusLost,
+	StatusRetired,
+}
+
+// deviceTransitions lists the statuses a device may move to from each status.
+// Staying checked out is a renewal; every other status only moves on.
+var deviceTransitions = map[DeviceStatus][]DeviceStatus{
+	StatusAvailable:  {StatusCheckedOut, StatusInRepair, StatusLost, StatusRetired},
+	StatusCheckedOut: {StatusAvailable, StatusCheckedOut, StatusInRepair, StatusLost, StatusRetired},
+	StatusInRepair:   {StatusAvailable, StatusLost, StatusRetired},
+	StatusLost:       {StatusAvailable, StatusRetired},
+	StatusRetired:    {},
+}
+
+// String returns the status as users write it, e.g. "in repair".
+func (s DeviceStatus) String() string {
+	return strings.ReplaceAll(string(s), "-", " ")
+}
+
+// Valid reports whether s is one of the known statuses.
+func (s DeviceStatus) Valid() bool {
+	_, ok := deviceTransitions[s]
+	return ok
+}
+
+// ClearsAssignment reports whether moving a device to s ends its checkout. Lost and
+// in-repair devices keep their assignee and dates, so it stays known who had them.
+func (s DeviceStatus) ClearsAssignment() bool {
+	return s == StatusAvailable || s == StatusRetired
+}
+
+// CanTransition reports whether a device may move from one status to another.
+func CanTransition(from, to DeviceStatus) bool {
+	return slices.Contains(deviceTransitions[from], to)
+}
+
+// TransitionSources lists the statuses a device may move to the given status from.
+func TransitionSources(to DeviceStatus) []DeviceStatus {
+	var sources []DeviceStatus
+	for _, from := range DeviceStatuses {
+		if CanTransition(from, to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+// ParseDeviceStatus parses a status as a user may write it: case does not matter and
+// words may be separated by spaces, hyphens or underscores, e.g. "In Repair" or "in_repair".
+func ParseDeviceStatus(s string) (DeviceStatus, error) {
+	normalized := strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), "-")
+
+	status := DeviceStatus(normalized)
+	if normalized == "checkedout" {
+		status = StatusCheckedOut
+	}
+	if normalized == "inrepair" || normalized == "repair" {
+		status = StatusInRepair
+	}
+	if !status.Valid() {
+		return "", fmt.Errorf("unknown device status %q", s)
+	}
+	return status, nil
+}
@@ -0,0 +1,37 @@
+package model
+
+import "testing"
+
+func TestParseDeviceStatus(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    DeviceStatus
+		wantErr bool
+	}{
+		{value: "available", want: StatusAvailable},
+		{value: "Available", want: StatusAvailable},
+		{value: "checked-out", want: StatusCheckedOut},
+		{value: "checked out", want: StatusCheckedOut},
+		{value: "checkedout", want: StatusCheckedOut},
+		{value: "In Repair", want: StatusInRepair},
+		{value: "in_repair", want: StatusInRepair},
+		{value: "repair", want: StatusInRepair},
+		{value: " lost ", want: StatusLost},
+		{value: "RETIRED", want: StatusRetired},
+		{value: "reserved", wantErr: true},
+		{value: "broken", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDeviceStatus(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDeviceStatus(%q) = %q, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDeviceStatus(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
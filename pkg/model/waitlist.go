@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// WaitlistEntry is one person waiting for a kind of device that had none available. The
+// waitlist is first come, first served among the entries a returned device matches.
+type WaitlistEntry struct {
+	EntryID     string         `dynamodbav:"EntryID"`
+	Query       string         `dynamodbav:"Query"`       // What they asked for, e.g. "iphone", in lower case
+	Requester   string         `dynamodbav:"Requester"`   // Email address, like Device.AssignedTo
+	SlackUserID string         `dynamodbav:"SlackUserID"` // Who to message when a device is held for them
+	JoinedAt    time.Time      `dynamodbav:"JoinedAt"`
+	Offer       *WaitlistOffer `dynamodbav:"Offer,omitempty"` // Set once a device is held for them
+}
+
+// WaitlistOffer is a device held for the person at the front of the waitlist. The hold is
+// a reservation, so nobody else can check the device out until it expires.
+type WaitlistOffer struct {
+	AssetTag      string    `dynamodbav:"AssetTag"`
+	ReservationID string    `dynamodbav:"ReservationID"`
+	OfferedAt     time.Time `dynamodbav:"OfferedAt"`
+	ExpiresAt     time.Time `dynamodbav:"ExpiresAt"`
+}
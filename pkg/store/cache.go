@@ -0,0 +1,283 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// CachingStore wraps a Store and caches device reads for a fixed TTL. Any write made
+// through it drops the cached list and the written device, so its own writes are seen
+// immediately; changes made elsewhere show up once the TTL expires.
+//
+// While the full list is cached, QueryDevices and IterateDevices are answered from it.
+// History is always read from the wrapped store.
+type CachingStore struct {
+	next Store
+	ttl  time.Duration
+
+	mu sync.Mutex
+	// generation is bumped by every invalidation. Reads only fill the cache if no write
+	// happened while they were in flight, so a slow read cannot cache stale data.
+	generation uint64
+	devices    map[string]cachedDevice
+	list       []model.Device
+	listExpiry time.Time
+}
+
+type cachedDevice struct {
+	device model.Device
+	expiry time.Time
+}
+
+var _ Store = (*CachingStore)(nil)
+
+// NewCachingStore returns a Store that caches reads from next for ttl.
+func NewCachingStore(next Store, ttl time.Duration) *CachingStore {
+	return &CachingStore{
+		next:    next,
+		ttl:     ttl,
+		devices: make(map[string]cachedDevice),
+	}
+}
+
+// Close closes the wrapped store.
+func (c *CachingStore) Close() error {
+	return c.next.Close()
+}
+
+// GetDevice returns the cached device, or reads it from the wrapped store.
+// Devices found in a fresh cached list are served from it too.
+func (c *CachingStore) GetDevice(ctx context.Context, deviceID string) (model.Device, error) {
+	c.mu.Lock()
+	now := time.Now()
+	if entry, ok := c.devices[deviceID]; ok && now.Before(entry.expiry) {
+		c.mu.Unlock()
+		return entry.device.Clone(), nil
+	}
+	if now.Before(c.listExpiry) {
+		for _, d := range c.list {
+			if d.AssetTag == deviceID {
+				c.mu.Unlock()
+				return d.Clone(), nil
+			}
+		}
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	device, err := c.next.GetDevice(ctx, deviceID)
+	if err != nil {
+		return model.Device{}, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.devices[deviceID] = cachedDevice{device: device.Clone(), expiry: time.Now().Add(c.ttl)}
+	}
+	c.mu.Unlock()
+
+	return device, nil
+}
+
+// ListDevices returns the cached inventory, or lists it from the wrapped store.
+func (c *CachingStore) ListDevices(ctx context.Context) ([]model.Device, error) {
+	if devices, ok := c.cachedList(); ok {
+		return devices, nil
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	devices, err := c.next.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.list = copyDevices(devices)
+		c.listExpiry = time.Now().Add(c.ttl)
+	}
+	c.mu.Unlock()
+
+	return devices, nil
+}
+
+// cachedList returns a copy of the cached inventory if it has not expired.
+func (c *CachingStore) cachedList() ([]model.Device, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !time.Now().Before(c.listExpiry) {
+		return nil, false
+	}
+	return copyDevices(c.list), true
+}
+
+// IterateDevices walks the cached inventory if it is fresh, and the wrapped store otherwise.
+func (c *CachingStore) IterateDevices(ctx context.Context, fn func(model.Device) error) error {
+	devices, ok := c.cachedList()
+	if !ok {
+		return c.next.IterateDevices(ctx, fn)
+	}
+
+	for _, device := range devices {
+		if err := fn(device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryDevices filters the cached inventory if it is fresh, and queries the wrapped store otherwise.
+func (c *CachingStore) QueryDevices(ctx context.Context, filter DeviceFilter) ([]model.Device, error) {
+	devices, ok := c.cachedList()
+	if !ok {
+		return c.next.QueryDevices(ctx, filter)
+	}
+
+	var matched []model.Device
+	for _, device := range devices {
+		if filter.Matches(device) {
+			matched = append(matched, device)
+		}
+	}
+	return matched, nil
+}
+
+// PutDevice writes through to the wrapped store.
+func (c *CachingStore) PutDevice(ctx context.Context, device model.Device) error {
+	defer c.invalidate(device.AssetTag)
+	return c.next.PutDevice(ctx, device)
+}
+
+// UpdateDevice writes through to the wrapped store.
+func (c *CachingStore) UpdateDevice(ctx context.Context, deviceID string, patch DevicePatch) error {
+	defer c.invalidate(deviceID)
+	return c.next.UpdateDevice(ctx, deviceID, patch)
+}
+
+// BatchPutDevices writes through to the wrapped store.
+func (c *CachingStore) BatchPutDevices(ctx context.Context, devices []model.Device) error {
+	defer func() {
+		for _, device := range devices {
+			c.invalidate(device.AssetTag)
+		}
+	}()
+	return c.next.BatchPutDevices(ctx, devices)
+}
+
+// BatchGetDevices answers from the cached inventory if it is fresh, and reads the
+// wrapped store otherwise.
+func (c *CachingStore) BatchGetDevices(ctx context.Context, deviceIDs []string) ([]model.Device, error) {
+	devices, ok := c.cachedList()
+	if !ok {
+		return c.next.BatchGetDevices(ctx, deviceIDs)
+	}
+
+	byTag := make(map[string]model.Device, len(devices))
+	for _, device := range devices {
+		byTag[device.AssetTag] = device
+	}
+
+	seen := make(map[string]bool, len(deviceIDs))
+	var found []model.Device
+	for _, id := range deviceIDs {
+		if device, ok := byTag[id]; ok && !seen[id] {
+			seen[id] = true
+			found = append(found, device)
+		}
+	}
+	return found, nil
+}
+
+// CheckoutDevice writes through to the wrapped store.
+func (c *CachingStore) CheckoutDevice(ctx context.Context, deviceID, assignee string, assignedAt, dueAt time.Time) error {
+	defer c.invalidate(deviceID)
+	return c.next.CheckoutDevice(ctx, deviceID, assignee, assignedAt, dueAt)
+}
+
+// ReturnDevice writes through to the wrapped store.
+func (c *CachingStore) ReturnDevice(ctx context.Context, deviceID, assignee string) error {
+	defer c.invalidate(deviceID)
+	return c.next.ReturnDevice(ctx, deviceID, assignee)
+}
+
+// DeleteDevice writes through to the wrapped store.
+func (c *CachingStore) DeleteDevice(ctx context.Context, deviceID string) error {
+	defer c.invalidate(deviceID)
+	return c.next.DeleteDevice(ctx, deviceID)
+}
+
+// SetDeviceStatus writes through to the wrapped store.
+func (c *CachingStore) SetDeviceStatus(ctx context.Context, deviceID string, status model.DeviceStatus) error {
+	defer c.invalidate(deviceID)
+	return c.next.SetDeviceStatus(ctx, deviceID, status)
+}
+
+// Reservations are never cached.
+
+func (c *CachingStore) CreateReservation(ctx context.Context, r model.Reservation) error {
+	return c.next.CreateReservation(ctx, r)
+}
+
+func (c *CachingStore) ListReservations(ctx context.Context, filter ReservationFilter) ([]model.Reservation, error) {
+	return c.next.ListReservations(ctx, filter)
+}
+
+func (c *CachingStore) CancelReservation(ctx context.Context, deviceID, reservationID string) error {
+	return c.next.CancelReservation(ctx, deviceID, reservationID)
+}
+
+// The waitlist is never cached.
+
+func (c *CachingStore) JoinWaitlist(ctx context.Context, e model.WaitlistEntry) error {
+	return c.next.JoinWaitlist(ctx, e)
+}
+
+func (c *CachingStore) ListWaitlist(ctx context.Context, filter WaitlistFilter) ([]model.WaitlistEntry, error) {
+	return c.next.ListWaitlist(ctx, filter)
+}
+
+func (c *CachingStore) OfferWaitlistEntry(ctx context.Context, entryID string, offer model.WaitlistOffer) error {
+	return c.next.OfferWaitlistEntry(ctx, entryID, offer)
+}
+
+func (c *CachingStore) LeaveWaitlist(ctx context.Context, entryID string) error {
+	return c.next.LeaveWaitlist(ctx, entryID)
+}
+
+// ListDeviceEvents is never cached.
+func (c *CachingStore) ListDeviceEvents(ctx context.Context, deviceID string) ([]model.DeviceEvent, error) {
+	return c.next.ListDeviceEvents(ctx, deviceID)
+}
+
+// IterateDeviceEvents is never cached.
+func (c *CachingStore) IterateDeviceEvents(ctx context.Context, fn func(model.DeviceEvent) error) error {
+	return c.next.IterateDeviceEvents(ctx, fn)
+}
+
+// invalidate drops the cached list and device. It runs even if the write failed, since
+// a failed write may still have changed the device.
+func (c *CachingStore) invalidate(deviceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	delete(c.devices, deviceID)
+	c.list = nil
+	c.listExpiry = time.Time{}
+}
+
+// copyDevices clones each device, so that callers cannot modify cached devices.
+func copyDevices(devices []model.Device) []model.Device {
+	copied := make([]model.Device, len(devices))
+	for i, d := range devices {
+		copied[i] = d.Clone()
+	}
+	return copied
+}
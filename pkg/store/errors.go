@@ -0,0 +1,208 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// Sentinel errors shared by every provider. Match them with errors.Is; the typed errors
+// below carry details and match the corresponding sentinel.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrUnsupported = errors.New("operation not supported")
+	ErrTransient   = errors.New("temporary failure")
+	ErrInvalid     = errors.New("invalid device")
+)
+
+// NotFoundError is returned when no device exists with the requested asset tag.
+type NotFoundError struct {
+	AssetTag string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("AssetTag %s not found", e.AssetTag)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ConflictError is returned when a checkout or return loses to the device's current holder,
+// e.g. the device is already checked out to someone else.
+type ConflictError struct {
+	AssetTag   string
+	AssignedTo string     // Current holder, empty if the device is not checked out
+	DueDate    *time.Time // When the current holder is due to return it, if known
+}
+
+func (e *ConflictError) Error() string {
+	if e.AssignedTo == "" {
+		return fmt.Sprintf("device %s is not checked out", e.AssetTag)
+	}
+	return fmt.Sprintf("device %s is already checked out to %s", e.AssetTag, e.AssignedTo)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ReservationNotFoundError is returned when a device has no reservation with the ID.
+type ReservationNotFoundError struct {
+	AssetTag      string
+	ReservationID string
+}
+
+func (e *ReservationNotFoundError) Error() string {
+	return fmt.Sprintf("reservation %s of %s not found", e.ReservationID, e.AssetTag)
+}
+
+func (e *ReservationNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ReservationConflictError is returned when a reservation overlaps another reservation of
+// the device, or the device is checked out past the reservation's start, and when a
+// checkout overlaps someone else's reservation. It matches ErrConflict.
+type ReservationConflictError struct {
+	AssetTag    string
+	Reservation *model.Reservation // The overlapping reservation, if that was the conflict
+	AssignedTo  string             // The current holder, if the checkout was the conflict
+	DueDate     *time.Time         // When the current holder is due to return it, if known
+}
+
+func (e *ReservationConflictError) Error() string {
+	if e.Reservation != nil {
+		return fmt.Sprintf("device %s is already reserved by %s from %s to %s", e.AssetTag,
+			e.Reservation.ReservedBy, e.Reservation.Start.Format(time.RFC3339), e.Reservation.End.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("device %s is checked out to %s", e.AssetTag, e.AssignedTo)
+}
+
+func (e *ReservationConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// WaitlistEntryNotFoundError is returned when no waitlist entry has the ID.
+type WaitlistEntryNotFoundError struct {
+	EntryID string
+}
+
+func (e *WaitlistEntryNotFoundError) Error() string {
+	return fmt.Sprintf("waitlist entry %s not found", e.EntryID)
+}
+
+func (e *WaitlistEntryNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// WaitlistConflictError is returned when a waitlist entry with the ID already exists, or
+// a device is already held for it. It matches ErrConflict.
+type WaitlistConflictError struct {
+	EntryID string
+	Offer   *model.WaitlistOffer // The existing hold, if that was the conflict
+}
+
+func (e *WaitlistConflictError) Error() string {
+	if e.Offer != nil {
+		return fmt.Sprintf("waitlist entry %s already holds device %s", e.EntryID, e.Offer.AssetTag)
+	}
+	return fmt.Sprintf("waitlist entry %s already exists", e.EntryID)
+}
+
+func (e *WaitlistConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ValidationError is returned when a device does not match the schema of its type, e.g.
+// a custom attribute has the wrong type. It matches ErrInvalid.
+type ValidationError struct {
+	AssetTag string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("device %s is invalid: %s", e.AssetTag, strings.Join(e.Problems, "; "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// TransitionError is returned when a device's lifecycle status cannot move to the status
+// a change needs, e.g. checking out a device that is in repair. It matches ErrConflict.
+type TransitionError struct {
+	AssetTag string
+	From     model.DeviceStatus
+	To       model.DeviceStatus
+}
+
+func (e *TransitionError) Error() string {
+	if e.To == model.StatusCheckedOut {
+		return fmt.Sprintf("device %s is %s and cannot be checked out", e.AssetTag, e.From)
+	}
+	return fmt.Sprintf("device %s cannot go from %s to %s", e.AssetTag, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// CheckTransition returns a *TransitionError unless the device's current status may move
+// to the given one. Providers call it for every change of status, inside the same
+// transaction or lock as the write.
+func CheckTransition(d model.Device, to model.DeviceStatus) error {
+	if from := d.CurrentStatus(); !model.CanTransition(from, to) {
+		return &TransitionError{AssetTag: d.AssetTag, From: from, To: to}
+	}
+	return nil
+}
+
+// ValidateStatus checks that SetDeviceStatus can move a device to status: it must be a
+// known status, and only CheckoutDevice can check a device out.
+func ValidateStatus(status model.DeviceStatus) error {
+	if !status.Valid() {
+		return fmt.Errorf("unknown device status %q", status)
+	}
+	if status == model.StatusCheckedOut {
+		return errors.New("devices can only be checked out with CheckoutDevice")
+	}
+	return nil
+}
+
+// Unsupported returns an error matching ErrUnsupported that explains why a provider
+// cannot perform an operation.
+func Unsupported(provider, reason string) error {
+	return fmt.Errorf("%s: %w: %s", provider, ErrUnsupported, reason)
+}
+
+// TransientError marks a failure as temporary, such as throttling or a network error,
+// so the same call may succeed if retried. It matches ErrTransient.
+type TransientError struct {
+	Err        error
+	RetryAfter time.Duration // How long the backend asked callers to wait, if it said
+}
+
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TransientError) Is(target error) bool {
+	return target == ErrTransient
+}
+
+// Transient marks err as temporary. It returns nil if err is nil.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TransientError{Err: err}
+}
@@ -10,17 +10,35 @@ const (
 	ProviderDynamoDB   = "dynamodb"
 	ProviderJiraAssets = "jira-api"
 	ProviderMemory     = "in-memory"
+	ProviderSQLite     = "sqlite"
 )
 
 type StoreConstructor func(ctx context.Context, cfg StoreConfig) (Store, error)
 
 // StoreConfig holds all necessary configuration strings for the database.
 type StoreConfig struct {
-	Provider         string
-	DynamoDBEndpoint string // e.g., "http://localhost:8000" or empty for AWS
-	JiraToken        string // e.g., "user=... password=..."
-	JiraBaseURL      string
-	JiraEmail        string
+	Provider                  string
+	DynamoDBEndpoint          string // e.g., "http://localhost:8000" or empty for AWS
+	DynamoDBRegion            string // e.g., "us-west-2"; empty uses the AWS environment or profile
+	DynamoDBProfile           string // named AWS profile; empty uses the default credential chain
+	DynamoDBTable             string // defaults to "Devices"
+	DynamoDBEventsTable       string // device history table, defaults to "DeviceEvents"
+	DynamoDBReservationsTable string // reservations table, defaults to "DeviceReservations"
+	DynamoDBWaitlistTable     string // waitlist table, defaults to "DeviceWaitlist"
+	DynamoDBBillingMode       string // "on-demand" (default) or "provisioned", applied when creating the table
+	DynamoDBReadCapacity      string // provisioned read capacity units, defaults to 1
+	DynamoDBWriteCapacity     string // provisioned write capacity units, defaults to 1
+	JiraToken                 string // e.g., "user=... password=..."
+	JiraBaseURL               string
+	JiraEmail                 string
+	JiraMappingFile           string // JSON file describing the Device-to-Jira-Assets mapping
+	JiraObjectTypeID          string // Overrides the mapping's object type ID
+	JiraAttributeIDs          string // Overrides mapped attribute IDs, e.g., "AssetTag=135,AssignedTo=140"
+	MemorySeedFile            string // optional JSON file of devices loaded by the in-memory provider
+	SQLitePath                string // e.g., "curator.db"
+	CacheTTL                  string // e.g., "30s"; empty or "0" disables the read cache
+	RetryMaxAttempts          string // attempts per store call, e.g., "4"; "1" disables retries
+	CallTimeout               string // e.g., "30s"; bounds each store call including retries
 }
 
 func NewStoreFactory(ctx context.Context, cfg StoreConfig, constructors map[string]StoreConstructor) (Store, error) {
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// Availability restricts a DeviceFilter to available or checked-out devices, going by
+// their lifecycle status: devices that are reserved, in repair or lost are neither.
+type Availability int
+
+const (
+	AnyAvailability Availability = iota
+	OnlyAvailable
+	OnlyCheckedOut
+)
+
+// DeviceFilter selects devices for Store.QueryDevices. Zero-value fields match everything.
+type DeviceFilter struct {
+	AssignedTo   string // Devices checked out to this person (case-insensitive)
+	DeviceType   string // Devices of this type (case-insensitive)
+	Location     string // Devices at this location (case-insensitive)
+	Availability Availability
+	DueBefore    *time.Time         // Devices with a due date strictly before this time
+	Status       model.DeviceStatus // Devices with this lifecycle status
+	// WarrantyBefore matches devices whose warranty expires strictly before this time.
+	WarrantyBefore *time.Time
+	// Attributes matches devices whose custom attributes have all of these values
+	// (case-insensitive), keyed by attribute key.
+	Attributes map[string]string
+	// IncludeRetired also matches retired devices, which are left out by default
+	// unless Status asks for them.
+	IncludeRetired bool
+}
+
+// Matches reports whether the device satisfies every condition of the filter.
+// Providers that can only narrow a query partially use it to finish the job in Go.
+func (f DeviceFilter) Matches(d model.Device) bool {
+	status := d.CurrentStatus()
+	if f.Status != "" && status != f.Status {
+		return false
+	}
+	if status == model.StatusRetired && !f.IncludeRetired && f.Status != model.StatusRetired {
+		return false
+	}
+	if f.AssignedTo != "" && !equalFoldTrim(d.AssignedTo, f.AssignedTo) {
+		return false
+	}
+	if f.DeviceType != "" && !equalFoldTrim(d.DeviceType, f.DeviceType) {
+		return false
+	}
+	if f.Location != "" && !equalFoldTrim(d.Location, f.Location) {
+		return false
+	}
+
+	switch f.Availability {
+	case OnlyAvailable:
+		if status != model.StatusAvailable {
+			return false
+		}
+	case OnlyCheckedOut:
+		if status != model.StatusCheckedOut {
+			return false
+		}
+	}
+
+	if f.DueBefore != nil && (d.DueDate == nil || !d.DueDate.Before(*f.DueBefore)) {
+		return false
+	}
+	if f.WarrantyBefore != nil && (d.WarrantyExpiration == nil || !d.WarrantyExpiration.Before(*f.WarrantyBefore)) {
+		return false
+	}
+	for key, value := range f.Attributes {
+		if !equalFoldTrim(d.Attributes[strings.ToLower(strings.TrimSpace(key))], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReservationFilter selects reservations for Store.ListReservations. Zero-value fields
+// match everything.
+type ReservationFilter struct {
+	AssetTag   string // Reservations of this device
+	ReservedBy string // Reservations made by this person (case-insensitive)
+	// From and To match reservations overlapping [From, To). Either may be nil to leave
+	// that end of the window open, e.g. From alone matches reservations not yet over.
+	From *time.Time
+	To   *time.Time
+}
+
+// Matches reports whether r satisfies every criterion of the filter.
+func (f ReservationFilter) Matches(r model.Reservation) bool {
+	if f.AssetTag != "" && r.AssetTag != f.AssetTag {
+		return false
+	}
+	if f.ReservedBy != "" && !equalFoldTrim(r.ReservedBy, f.ReservedBy) {
+		return false
+	}
+	if f.From != nil && !f.From.Before(r.End) {
+		return false
+	}
+	if f.To != nil && !r.Start.Before(*f.To) {
+		return false
+	}
+	return true
+}
+
+func equalFoldTrim(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// WaitlistFilter selects waitlist entries for Store.ListWaitlist. Zero-value fields match
+// everything.
+type WaitlistFilter struct {
+	Query     string // Entries waiting for exactly this query (case-insensitive)
+	Requester string // Entries of this person (case-insensitive)
+	AssetTag  string // Entries holding this device
+}
+
+// Matches reports whether e satisfies every criterion of the filter.
+func (f WaitlistFilter) Matches(e model.WaitlistEntry) bool {
+	if f.Query != "" && !equalFoldTrim(e.Query, f.Query) {
+		return false
+	}
+	if f.Requester != "" && !equalFoldTrim(e.Requester, f.Requester) {
+		return false
+	}
+	if f.AssetTag != "" && (e.Offer == nil || e.Offer.AssetTag != f.AssetTag) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// SystemActor is recorded as the actor of changes made without WithActor.
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// WithActor returns a context that attributes the store changes made with it to actor
+// in device history.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor, or SystemActor.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+type writeIDKey struct{}
+
+// WithWriteID returns a context whose store writes record id in the events they append,
+// so that the device's history shows whether a write went through. The ID must be used
+// for a single write. RetryingStore sets one for each write that has none, to avoid
+// applying a write twice.
+func WithWriteID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, writeIDKey{}, id)
+}
+
+// WriteIDFromContext returns the write ID set with WithWriteID, or "".
+func WriteIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(writeIDKey{}).(string)
+	return id
+}
+
+// withWriteID returns ctx with a new random write ID, unless it already has one.
+func withWriteID(ctx context.Context) context.Context {
+	if WriteIDFromContext(ctx) != "" {
+		return ctx
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	return WithWriteID(ctx, hex.EncodeToString(id))
+}
+
+// eventTimeLayout is fixed-width so that event IDs sort chronologically as strings.
+const eventTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// DeviceEventID builds an event ID that sorts by t, made unique by suffix.
+func DeviceEventID(t time.Time, suffix string) string {
+	return t.UTC().Format(eventTimeLayout) + "-" + suffix
+}
+
+// NewDeviceEvent records a change from before to after, made now by the context's actor
+// as part of its write. before is nil when the device was created. Providers call it for
+// every write.
+func NewDeviceEvent(ctx context.Context, before *model.Device, after model.Device) model.DeviceEvent {
+	now := time.Now().UTC()
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	return model.DeviceEvent{
+		AssetTag:  after.AssetTag,
+		EventID:   DeviceEventID(now, hex.EncodeToString(suffix)),
+		Type:      model.ClassifyDeviceChange(before, after),
+		Actor:     ActorFromContext(ctx),
+		Timestamp: now,
+		Before:    before,
+		After:     &after,
+		WriteID:   WriteIDFromContext(ctx),
+	}
+}
+
+// NewDeviceDeletedEvent records the deletion of a device, made now by the context's actor.
+func NewDeviceDeletedEvent(ctx context.Context, before model.Device) model.DeviceEvent {
+	event := NewDeviceEvent(ctx, &before, before)
+	event.Type = model.DeviceDeleted
+	event.After = nil
+	return event
+}
+
+// ChangesDevice reports whether a write from before to after is worth recording.
+// Every write that creates a device or changes one of its fields is.
+func ChangesDevice(before *model.Device, after model.Device) bool {
+	return before == nil || len(model.DiffDevices(*before, after)) > 0
+}
+
+// ReplayDevice returns the state of a device at the given time from its history, oldest
+// event first. It reports false if the history shows the device was created after at.
+// Without any history the device is assumed to have been in its current state all along,
+// so the caller should fall back to the current device.
+func ReplayDevice(events []model.DeviceEvent, at time.Time) (model.Device, bool) {
+	var state *model.Device
+	for _, e := range events {
+		if e.Timestamp.After(at) {
+			if state == nil {
+				// Nothing happened before at: the state then is what the first later event changed from.
+				state = e.Before
+			}
+			break
+		}
+		state = e.After
+	}
+
+	if state == nil {
+		return model.Device{}, false
+	}
+	return *state, true
+}
+
+// DeviceAsOf rebuilds a device as it was at the given time by replaying its history.
+// Deleted devices are rebuilt from the history they leave behind. It reports false if
+// the device did not exist yet or was already deleted, and returns a *NotFoundError if
+// there is no such device now and no history of one.
+func DeviceAsOf(ctx context.Context, s Store, deviceID string, at time.Time) (model.Device, bool, error) {
+	current, err := s.GetDevice(ctx, deviceID)
+	deleted := errors.Is(err, ErrNotFound)
+	if err != nil && !deleted {
+		return model.Device{}, false, err
+	}
+
+	events, err := s.ListDeviceEvents(ctx, deviceID)
+	if err != nil {
+		return model.Device{}, false, err
+	}
+	if len(events) == 0 {
+		if deleted {
+			return model.Device{}, false, &NotFoundError{AssetTag: deviceID}
+		}
+		return current, true, nil
+	}
+
+	device, ok := ReplayDevice(events, at)
+	return device, ok, nil
+}
+
+// InventoryAsOf rebuilds the inventory as it was at the given time from the event log,
+// sorted by asset tag. Devices deleted since are included, and devices that did not
+// exist yet or were already deleted are left out. Current devices without any history
+// are included as they are now, like DeviceAsOf does. The whole log is held in memory.
+func InventoryAsOf(ctx context.Context, s Store, at time.Time) ([]model.Device, error) {
+	history := make(map[string][]model.DeviceEvent)
+	err := s.IterateDeviceEvents(ctx, func(e model.DeviceEvent) error {
+		history[e.AssetTag] = append(history[e.AssetTag], e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []model.Device
+	err = s.IterateDevices(ctx, func(current model.Device) error {
+		if _, ok := history[current.AssetTag]; !ok {
+			devices = append(devices, current)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, events := range history {
+		// Events come in no particular order; those at the same instant keep theirs.
+		slices.SortStableFunc(events, func(a, b model.DeviceEvent) int {
+			return a.Timestamp.Compare(b.Timestamp)
+		})
+		if device, ok := ReplayDevice(events, at); ok {
+			devices = append(devices, device)
+		}
+	}
+
+	slices.SortFunc(devices, func(a, b model.Device) int {
+		return strings.Compare(a.AssetTag, b.AssetTag)
+	})
+	return devices, nil
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+type patchOp int
+
+const (
+	keepField patchOp = iota
+	setField
+	clearField
+)
+
+// Field is one field of a DevicePatch. The zero value leaves the field unchanged; use Set
+// or Clear to change it.
+type Field[T any] struct {
+	op    patchOp
+	value T
+}
+
+// Set returns a Field that sets the field to value.
+func Set[T any](value T) Field[T] {
+	return Field[T]{op: setField, value: value}
+}
+
+// Clear returns a Field that removes the field's value, leaving it empty, nil or zero.
+func Clear[T any]() Field[T] {
+	return Field[T]{op: clearField}
+}
+
+// Get returns the value the field is set to, and false if it is not being set.
+func (f Field[T]) Get() (T, bool) {
+	return f.value, f.op == setField
+}
+
+// IsClear reports whether the field is being cleared.
+func (f Field[T]) IsClear() bool {
+	return f.op == clearField
+}
+
+// Changed reports whether the field is being set or cleared.
+func (f Field[T]) Changed() bool {
+	return f.op != keepField
+}
+
+// DevicePatch is a partial update of a device for Store.UpdateDevice. It has one Field per
+// mutable model.Device field. The AssetTag cannot be changed, and the Status and assignment
+// only move through CheckoutDevice, ReturnDevice and SetDeviceStatus so that every
+// transition is checked.
+type DevicePatch struct {
+	DeviceType  Field[string]
+	DeviceMake  Field[string]
+	DeviceModel Field[string]
+	Location    Field[string]
+
+	SerialNumber       Field[string]
+	Vendor             Field[string]
+	PurchaseDate       Field[time.Time]
+	PurchaseCost       Field[int64] // In cents
+	WarrantyExpiration Field[time.Time]
+
+	// Attributes sets or clears custom attributes by key. Attributes it leaves out keep
+	// their values.
+	Attributes map[string]Field[string]
+}
+
+// PatchedField is one field changed by a DevicePatch.
+type PatchedField struct {
+	Name string // The model.Device field name, e.g. "Location", or a model.AttributeField
+	// Value is the field's new value: a string, *time.Time or int64. Cleared fields hold
+	// their zero value ("", a nil *time.Time or 0).
+	Value   interface{}
+	Cleared bool
+}
+
+// ErrEmptyPatch is returned by DevicePatch.Validate for a patch that changes nothing.
+var ErrEmptyPatch = errors.New("device patch changes no fields")
+
+// Validate checks that the patch changes at least one field and that every value it sets
+// is meaningful: empty strings, zero times and zero costs must be cleared rather than set,
+// and costs cannot be negative.
+func (p DevicePatch) Validate() error {
+	fields := p.Fields()
+	if len(fields) == 0 {
+		return ErrEmptyPatch
+	}
+
+	for _, f := range fields {
+		if f.Cleared {
+			continue
+		}
+		switch v := f.Value.(type) {
+		case string:
+			if v == "" {
+				return fmt.Errorf("cannot set %s to an empty string, clear it instead", f.Name)
+			}
+		case *time.Time:
+			if v.IsZero() {
+				return fmt.Errorf("cannot set %s to the zero time, clear it instead", f.Name)
+			}
+		case int64:
+			if v == 0 {
+				return fmt.Errorf("cannot set %s to zero, clear it instead", f.Name)
+			}
+			if v < 0 {
+				return fmt.Errorf("cannot set %s to a negative amount", f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Fields lists the fields the patch changes, in model.Device field order.
+func (p DevicePatch) Fields() []PatchedField {
+	var fields []PatchedField
+	text := func(name string, f Field[string]) {
+		if f.Changed() {
+			fields = append(fields, PatchedField{Name: name, Value: f.value, Cleared: f.IsClear()})
+		}
+	}
+	date := func(name string, f Field[time.Time]) {
+		switch {
+		case f.IsClear():
+			fields = append(fields, PatchedField{Name: name, Value: (*time.Time)(nil), Cleared: true})
+		case f.Changed():
+			t := f.value
+			fields = append(fields, PatchedField{Name: name, Value: &t})
+		}
+	}
+
+	text("DeviceType", p.DeviceType)
+	text("DeviceMake", p.DeviceMake)
+	text("DeviceModel", p.DeviceModel)
+	text("Location", p.Location)
+	text("SerialNumber", p.SerialNumber)
+	text("Vendor", p.Vendor)
+	date("PurchaseDate", p.PurchaseDate)
+	if p.PurchaseCost.Changed() {
+		fields = append(fields, PatchedField{Name: "PurchaseCost", Value: p.PurchaseCost.value, Cleared: p.PurchaseCost.IsClear()})
+	}
+	date("WarrantyExpiration", p.WarrantyExpiration)
+
+	keys := make([]string, 0, len(p.Attributes))
+	for key := range p.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		text(model.AttributeField(key), p.Attributes[key])
+	}
+	return fields
+}
+
+// Apply returns a copy of d with the patch applied.
+func (p DevicePatch) Apply(d model.Device) model.Device {
+	text := func(dst *string, f Field[string]) {
+		if f.Changed() {
+			*dst = f.value
+		}
+	}
+	date := func(dst **time.Time, f Field[time.Time]) {
+		switch {
+		case f.IsClear():
+			*dst = nil
+		case f.Changed():
+			t := f.value
+			*dst = &t
+		}
+	}
+
+	text(&d.DeviceType, p.DeviceType)
+	text(&d.DeviceMake, p.DeviceMake)
+	text(&d.DeviceModel, p.DeviceModel)
+	text(&d.Location, p.Location)
+	text(&d.SerialNumber, p.SerialNumber)
+	text(&d.Vendor, p.Vendor)
+	date(&d.PurchaseDate, p.PurchaseDate)
+	if p.PurchaseCost.Changed() {
+		d.PurchaseCost = p.PurchaseCost.value
+	}
+	date(&d.WarrantyExpiration, p.WarrantyExpiration)
+
+	if len(p.Attributes) > 0 {
+		attrs := make(map[string]string, len(d.Attributes)+len(p.Attributes))
+		for key, value := range d.Attributes {
+			attrs[key] = value
+		}
+		for key, f := range p.Attributes {
+			switch {
+			case f.IsClear():
+				delete(attrs, key)
+			case f.Changed():
+				attrs[key] = f.value
+			}
+		}
+		if len(attrs) == 0 {
+			attrs = nil
+		}
+		d.Attributes = attrs
+	}
+	return d
+}
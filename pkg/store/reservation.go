@@ -0,0 +1,96 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// NewReservation returns a reservation of a device over [start, end) with a new ID.
+// The ID is set by the caller rather than the provider so that retrying a create that
+// may have succeeded cannot book the device twice.
+func NewReservation(assetTag, reservedBy string, start, end time.Time) model.Reservation {
+	id := make([]byte, 4)
+	rand.Read(id)
+
+	return model.Reservation{
+		ReservationID: hex.EncodeToString(id),
+		AssetTag:      assetTag,
+		ReservedBy:    reservedBy,
+		Start:         start,
+		End:           end,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+// ValidateReservation checks that a reservation is complete and ends after it starts.
+func ValidateReservation(r model.Reservation) error {
+	switch {
+	case r.ReservationID == "":
+		return errors.New("reservation is missing ReservationID")
+	case r.AssetTag == "":
+		return errors.New("reservation is missing AssetTag")
+	case strings.TrimSpace(r.ReservedBy) == "":
+		return errors.New("reservation is missing ReservedBy")
+	case !r.Start.Before(r.End):
+		return errors.New("reservation must end after it starts")
+	}
+	return nil
+}
+
+// CheckReservation returns a *ReservationConflictError if r overlaps one of the device's
+// existing reservations, or the device is checked out to someone else past r's start,
+// and a *TransitionError if the device is retired, since it could never be checked out.
+// Providers call it inside the same transaction or lock as the write.
+func CheckReservation(d model.Device, existing []model.Reservation, r model.Reservation) error {
+	if status := d.CurrentStatus(); status == model.StatusRetired {
+		return &TransitionError{AssetTag: d.AssetTag, From: status, To: model.StatusCheckedOut}
+	}
+	if d.CurrentStatus() == model.StatusCheckedOut && !equalFoldTrim(d.AssignedTo, r.ReservedBy) &&
+		(d.DueDate == nil || r.Start.Before(*d.DueDate)) {
+		return &ReservationConflictError{AssetTag: d.AssetTag, AssignedTo: d.AssignedTo, DueDate: d.DueDate}
+	}
+
+	existing = slices.Clone(existing)
+	SortReservations(existing)
+	for _, other := range existing {
+		if other.Overlaps(r.Start, r.End) {
+			return &ReservationConflictError{AssetTag: d.AssetTag, Reservation: &other}
+		}
+	}
+	return nil
+}
+
+// CheckCheckout returns a *ReservationConflictError if one of the device's reservations
+// by someone other than assignee overlaps a checkout from assignedAt until dueAt.
+// Providers call it inside the same transaction or lock as the checkout.
+func CheckCheckout(assetTag, assignee string, reservations []model.Reservation, assignedAt, dueAt time.Time) error {
+	reservations = slices.Clone(reservations)
+	SortReservations(reservations)
+	for _, r := range reservations {
+		if !equalFoldTrim(r.ReservedBy, assignee) && r.Overlaps(assignedAt, dueAt) {
+			return &ReservationConflictError{AssetTag: assetTag, Reservation: &r}
+		}
+	}
+	return nil
+}
+
+// SortReservations orders reservations by start, then by asset tag.
+func SortReservations(reservations []model.Reservation) {
+	sort.Slice(reservations, func(i, j int) bool {
+		a, b := reservations[i], reservations[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.AssetTag != b.AssetTag {
+			return a.AssetTag < b.AssetTag
+		}
+		return a.ReservationID < b.ReservationID
+	})
+}
@@ -0,0 +1,359 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// RetryPolicy controls how RetryingStore retries failed calls.
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts per call, including the first. 1 disables retries
+	BaseDelay   time.Duration // Upper bound of the first backoff; doubles on every retry
+	MaxDelay    time.Duration // Upper bound of any single backoff
+	// Timeout bounds each call, including its retries, when the caller's context has
+	// no deadline. Zero leaves such calls unbounded.
+	Timeout time.Duration
+	// Retryable reports whether a failed call may be retried. Defaults to IsRetryable.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Timeout:     30 * time.Second,
+		Retryable:   IsRetryable,
+	}
+}
+
+// IsRetryable reports whether err is temporary: an error marked with Transient, or a
+// network timeout.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryingStore wraps a Store and retries calls that fail with a retryable error, waiting
+// a jittered, exponentially growing delay between attempts. It never waits past the
+// deadline of the caller's context.
+type RetryingStore struct {
+	next   Store
+	policy RetryPolicy
+}
+
+var _ Store = (*RetryingStore)(nil)
+
+// NewRetryingStore returns a Store that retries calls to next according to policy.
+func NewRetryingStore(next Store, policy RetryPolicy) *RetryingStore {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+	return &RetryingStore{next: next, policy: policy}
+}
+
+// retryCall runs op until it succeeds, fails with an error that is not retryable, or runs
+// out of attempts or time. The last error is returned.
+func retryCall[T any](ctx context.Context, r *RetryingStore, name string, op func(context.Context) (T, error)) (T, error) {
+	if _, ok := ctx.Deadline(); !ok && r.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.policy.Timeout)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := op(ctx)
+		var permanent permanentError
+		if err == nil || attempt >= r.policy.MaxAttempts || errors.As(err, &permanent) || !r.policy.Retryable(err) {
+			return result, err
+		}
+		// The caller gave up; a timeout of the attempt itself is worth retrying.
+		if ctx.Err() != nil {
+			return result, err
+		}
+
+		delay := r.backoff(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return result, err
+		}
+
+		log.Printf("Store %s failed (attempt %d/%d), retrying in %s: %v", name, attempt, r.policy.MaxAttempts, delay.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns a random delay up to BaseDelay*2^(attempt-1), capped at MaxDelay,
+// or the delay the backend asked for if that is longer.
+func (r *RetryingStore) backoff(attempt int, err error) time.Duration {
+	ceiling := r.policy.MaxDelay
+	if shift := attempt - 1; shift < 32 && r.policy.BaseDelay<<shift < ceiling {
+		ceiling = r.policy.BaseDelay << shift
+	}
+
+	var delay time.Duration
+	if ceiling > 0 {
+		delay = rand.N(ceiling) + 1
+	}
+
+	var transient *TransientError
+	if errors.As(err, &transient) && transient.RetryAfter > delay {
+		delay = transient.RetryAfter
+	}
+	return delay
+}
+
+// retryErr is retryCall for operations that only return an error.
+func retryErr(ctx context.Context, r *RetryingStore, name string, op func(context.Context) error) error {
+	_, err := retryCall(ctx, r, name, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, op(ctx)
+	})
+	return err
+}
+
+// Close closes the wrapped store.
+func (r *RetryingStore) Close() error {
+	return r.next.Close()
+}
+
+func (r *RetryingStore) PutDevice(ctx context.Context, device model.Device) error {
+	return retryDeviceWrite(ctx, r, "PutDevice", device.AssetTag, func(ctx context.Context) error {
+		return r.next.PutDevice(ctx, device)
+	})
+}
+
+func (r *RetryingStore) GetDevice(ctx context.Context, deviceID string) (model.Device, error) {
+	return retryCall(ctx, r, "GetDevice", func(ctx context.Context) (model.Device, error) {
+		return r.next.GetDevice(ctx, deviceID)
+	})
+}
+
+func (r *RetryingStore) ListDevices(ctx context.Context) ([]model.Device, error) {
+	return retryCall(ctx, r, "ListDevices", r.next.ListDevices)
+}
+
+// IterateDevices only retries failures that happen before the first device reaches fn,
+// so fn never sees a device twice.
+func (r *RetryingStore) IterateDevices(ctx context.Context, fn func(model.Device) error) error {
+	return retryIterate(ctx, r, "IterateDevices", r.next.IterateDevices, fn)
+}
+
+// retryIterate runs an iteration, retrying failures only until the first item reaches fn.
+func retryIterate[T any](ctx context.Context, r *RetryingStore, name string, iterate func(context.Context, func(T) error) error, fn func(T) error) error {
+	started := false
+	err := retryErr(ctx, r, name, func(ctx context.Context) error {
+		err := iterate(ctx, func(item T) error {
+			started = true
+			return fn(item)
+		})
+		if err != nil && started {
+			return permanentError{err}
+		}
+		return err
+	})
+
+	var permanent permanentError
+	if errors.As(err, &permanent) {
+		return permanent.err
+	}
+	return err
+}
+
+// permanentError carries an error through retryCall without it being retried.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// retryWrite is retryErr for writes. An attempt that failed may still have been applied,
+// for example when only its response was lost, and running it again could then fail
+// or record the change twice. So before each retry, applied reports whether the write
+// went through, and if it did retryWrite succeeds without running it again.
+func retryWrite(ctx context.Context, r *RetryingStore, name string, op func(context.Context) error, applied func(context.Context) (bool, error)) error {
+	attempted := false
+	return retryErr(ctx, r, name, func(ctx context.Context) error {
+		if attempted {
+			done, err := applied(ctx)
+			if err != nil {
+				return err
+			}
+			if done {
+				return nil
+			}
+		}
+		attempted = true
+		return op(ctx)
+	})
+}
+
+// retryDeviceWrite runs a write to one device with retryWrite. Every attempt carries the
+// same write ID, the caller's or a new one, so the write was applied if the device's
+// history has an event with it. An attempt that changed nothing leaves no event, and
+// running it again is harmless.
+func retryDeviceWrite(ctx context.Context, r *RetryingStore, name, deviceID string, op func(context.Context) error) error {
+	ctx = withWriteID(ctx)
+	writeID := WriteIDFromContext(ctx)
+	return retryWrite(ctx, r, name, op, func(ctx context.Context) (bool, error) {
+		events, err := r.next.ListDeviceEvents(ctx, deviceID)
+		if err != nil {
+			return false, err
+		}
+		return slices.ContainsFunc(events, func(e model.DeviceEvent) bool { return e.WriteID == writeID }), nil
+	})
+}
+
+func (r *RetryingStore) QueryDevices(ctx context.Context, filter DeviceFilter) ([]model.Device, error) {
+	return retryCall(ctx, r, "QueryDevices", func(ctx context.Context) ([]model.Device, error) {
+		return r.next.QueryDevices(ctx, filter)
+	})
+}
+
+func (r *RetryingStore) UpdateDevice(ctx context.Context, deviceID string, patch DevicePatch) error {
+	return retryDeviceWrite(ctx, r, "UpdateDevice", deviceID, func(ctx context.Context) error {
+		return r.next.UpdateDevice(ctx, deviceID, patch)
+	})
+}
+
+// BatchPutDevices is retried as is rather than reading the history of every device:
+// writing the devices again records no change for those already written.
+func (r *RetryingStore) BatchPutDevices(ctx context.Context, devices []model.Device) error {
+	return retryErr(withWriteID(ctx), r, "BatchPutDevices", func(ctx context.Context) error {
+		return r.next.BatchPutDevices(ctx, devices)
+	})
+}
+
+func (r *RetryingStore) BatchGetDevices(ctx context.Context, deviceIDs []string) ([]model.Device, error) {
+	return retryCall(ctx, r, "BatchGetDevices", func(ctx context.Context) ([]model.Device, error) {
+		return r.next.BatchGetDevices(ctx, deviceIDs)
+	})
+}
+
+func (r *RetryingStore) CheckoutDevice(ctx context.Context, deviceID, assignee string, assignedAt, dueAt time.Time) error {
+	return retryDeviceWrite(ctx, r, "CheckoutDevice", deviceID, func(ctx context.Context) error {
+		return r.next.CheckoutDevice(ctx, deviceID, assignee, assignedAt, dueAt)
+	})
+}
+
+func (r *RetryingStore) ReturnDevice(ctx context.Context, deviceID, assignee string) error {
+	return retryDeviceWrite(ctx, r, "ReturnDevice", deviceID, func(ctx context.Context) error {
+		return r.next.ReturnDevice(ctx, deviceID, assignee)
+	})
+}
+
+func (r *RetryingStore) DeleteDevice(ctx context.Context, deviceID string) error {
+	return retryDeviceWrite(ctx, r, "DeleteDevice", deviceID, func(ctx context.Context) error {
+		return r.next.DeleteDevice(ctx, deviceID)
+	})
+}
+
+func (r *RetryingStore) SetDeviceStatus(ctx context.Context, deviceID string, status model.DeviceStatus) error {
+	return retryDeviceWrite(ctx, r, "SetDeviceStatus", deviceID, func(ctx context.Context) error {
+		return r.next.SetDeviceStatus(ctx, deviceID, status)
+	})
+}
+
+// CreateReservation was applied if the device has a reservation with its ID.
+func (r *RetryingStore) CreateReservation(ctx context.Context, res model.Reservation) error {
+	return retryWrite(ctx, r, "CreateReservation", func(ctx context.Context) error {
+		return r.next.CreateReservation(ctx, res)
+	}, func(ctx context.Context) (bool, error) {
+		existing, err := r.next.ListReservations(ctx, ReservationFilter{AssetTag: res.AssetTag})
+		if err != nil {
+			return false, err
+		}
+		return slices.ContainsFunc(existing, func(e model.Reservation) bool { return e.ReservationID == res.ReservationID }), nil
+	})
+}
+
+func (r *RetryingStore) ListReservations(ctx context.Context, filter ReservationFilter) ([]model.Reservation, error) {
+	return retryCall(ctx, r, "ListReservations", func(ctx context.Context) ([]model.Reservation, error) {
+		return r.next.ListReservations(ctx, filter)
+	})
+}
+
+// CancelReservation is retried as is. A retry after an attempt that went through finds
+// the reservation gone and returns a *ReservationNotFoundError, as it would if anyone
+// else had cancelled it; callers that only need it gone can ignore that.
+func (r *RetryingStore) CancelReservation(ctx context.Context, deviceID, reservationID string) error {
+	return retryErr(ctx, r, "CancelReservation", func(ctx context.Context) error {
+		return r.next.CancelReservation(ctx, deviceID, reservationID)
+	})
+}
+
+// JoinWaitlist was applied if the waitlist has an entry with its ID.
+func (r *RetryingStore) JoinWaitlist(ctx context.Context, e model.WaitlistEntry) error {
+	return retryWrite(ctx, r, "JoinWaitlist", func(ctx context.Context) error {
+		return r.next.JoinWaitlist(ctx, e)
+	}, func(ctx context.Context) (bool, error) {
+		_, found, err := r.waitlistEntry(ctx, e.EntryID, e.Requester)
+		return found, err
+	})
+}
+
+func (r *RetryingStore) ListWaitlist(ctx context.Context, filter WaitlistFilter) ([]model.WaitlistEntry, error) {
+	return retryCall(ctx, r, "ListWaitlist", func(ctx context.Context) ([]model.WaitlistEntry, error) {
+		return r.next.ListWaitlist(ctx, filter)
+	})
+}
+
+// OfferWaitlistEntry was applied if the entry holds the offer's reservation.
+func (r *RetryingStore) OfferWaitlistEntry(ctx context.Context, entryID string, offer model.WaitlistOffer) error {
+	return retryWrite(ctx, r, "OfferWaitlistEntry", func(ctx context.Context) error {
+		return r.next.OfferWaitlistEntry(ctx, entryID, offer)
+	}, func(ctx context.Context) (bool, error) {
+		e, found, err := r.waitlistEntry(ctx, entryID, "")
+		return found && e.Offer != nil && e.Offer.ReservationID == offer.ReservationID, err
+	})
+}
+
+// waitlistEntry looks up an entry by ID, among the requester's if one is given.
+func (r *RetryingStore) waitlistEntry(ctx context.Context, entryID, requester string) (model.WaitlistEntry, bool, error) {
+	entries, err := r.next.ListWaitlist(ctx, WaitlistFilter{Requester: requester})
+	if err != nil {
+		return model.WaitlistEntry{}, false, err
+	}
+	for _, e := range entries {
+		if e.EntryID == entryID {
+			return e, true, nil
+		}
+	}
+	return model.WaitlistEntry{}, false, nil
+}
+
+// LeaveWaitlist is retried as is, like CancelReservation: a retry after an attempt that
+// went through returns a *WaitlistEntryNotFoundError.
+func (r *RetryingStore) LeaveWaitlist(ctx context.Context, entryID string) error {
+	return retryErr(ctx, r, "LeaveWaitlist", func(ctx context.Context) error {
+		return r.next.LeaveWaitlist(ctx, entryID)
+	})
+}
+
+func (r *RetryingStore) ListDeviceEvents(ctx context.Context, deviceID string) ([]model.DeviceEvent, error) {
+	return retryCall(ctx, r, "ListDeviceEvents", func(ctx context.Context) ([]model.DeviceEvent, error) {
+		return r.next.ListDeviceEvents(ctx, deviceID)
+	})
+}
+
+// IterateDeviceEvents, like IterateDevices, only retries failures before the first event
+// reaches fn.
+func (r *RetryingStore) IterateDeviceEvents(ctx context.Context, fn func(model.DeviceEvent) error) error {
+	return retryIterate(ctx, r, "IterateDeviceEvents", r.next.IterateDeviceEvents, fn)
+}
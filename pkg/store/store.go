@@ -3,6 +3,7 @@ package store
 import (
 	"bdemetris/curator/pkg/model"
 	"context"
+	"time"
 )
 
 // Store defines the methods for interacting with the database.
@@ -14,5 +15,79 @@ type Store interface {
 	PutDevice(ctx context.Context, device model.Device) error
 	GetDevice(ctx context.Context, deviceID string) (model.Device, error)
 	ListDevices(ctx context.Context) ([]model.Device, error)
-	UpdateDevice(ctx context.Context, deviceID string, updates map[string]interface{}) error
+	// IterateDevices calls fn for every device without loading the whole inventory at once.
+	// Iteration stops at the first error returned by fn, which is returned to the caller.
+	IterateDevices(ctx context.Context, fn func(model.Device) error) error
+	// QueryDevices returns the devices matching filter, using provider indexes where possible.
+	QueryDevices(ctx context.Context, filter DeviceFilter) ([]model.Device, error)
+	// UpdateDevice changes only the fields set or cleared by patch, returning a
+	// *NotFoundError if the device does not exist and an error if patch.Validate fails.
+	UpdateDevice(ctx context.Context, deviceID string, patch DevicePatch) error
+	// BatchPutDevices stores many devices at once, replacing existing ones like PutDevice.
+	// If several devices share an AssetTag, the last one wins.
+	BatchPutDevices(ctx context.Context, devices []model.Device) error
+	// BatchGetDevices returns the devices with the given asset tags, each once, in the order
+	// asked for. Unknown asset tags are left out rather than reported as errors.
+	BatchGetDevices(ctx context.Context, deviceIDs []string) ([]model.Device, error)
+
+	// CheckoutDevice atomically assigns an available device and marks it checked out. It
+	// returns a *ConflictError naming the current holder if the device is already checked
+	// out to someone else, a *TransitionError if its status does not allow a checkout,
+	// e.g. it is in repair, and a *ReservationConflictError if the checkout overlaps a
+	// reservation by someone else.
+	CheckoutDevice(ctx context.Context, deviceID, assignee string, assignedAt, dueAt time.Time) error
+	// ReturnDevice atomically clears the assignment and marks the device available, failing
+	// with a *ConflictError unless the device is currently checked out to assignee.
+	ReturnDevice(ctx context.Context, deviceID, assignee string) error
+
+	// DeleteDevice permanently removes a device and cancels its reservations. Its history
+	// is kept and ends with a delete event. Deleting an unknown device returns a
+	// *NotFoundError. Providers that cannot keep the history of a deleted device return an
+	// error matching ErrUnsupported instead; such devices can only be retired.
+	DeleteDevice(ctx context.Context, deviceID string) error
+	// SetDeviceStatus moves a device to another lifecycle status, clearing any assignment
+	// if the status is one that model.DeviceStatus.ClearsAssignment ends a checkout with.
+	// It returns a *TransitionError if the device's current status cannot move there and
+	// an error if ValidateStatus rejects status. Retired devices stay in the inventory for
+	// history, but can never change again and are left out of queries unless
+	// DeviceFilter.IncludeRetired is set.
+	SetDeviceStatus(ctx context.Context, deviceID string, status model.DeviceStatus) error
+
+	// Reservation Operations
+	// CreateReservation books a device over [r.Start, r.End). The check and the write are
+	// atomic: it returns a *ReservationConflictError if the window overlaps another
+	// reservation of the device or the device is checked out to someone else past r.Start,
+	// a *TransitionError if the device is retired, a *NotFoundError if it does not exist,
+	// and an error if ValidateReservation rejects r. Use NewReservation to build r.
+	CreateReservation(ctx context.Context, r model.Reservation) error
+	// ListReservations returns the reservations matching filter, ordered by SortReservations.
+	ListReservations(ctx context.Context, filter ReservationFilter) ([]model.Reservation, error)
+	// CancelReservation deletes a reservation of the device, returning a
+	// *ReservationNotFoundError if it has none with the ID.
+	CancelReservation(ctx context.Context, deviceID, reservationID string) error
+
+	// Waitlist Operations
+	// JoinWaitlist adds e to the back of the waitlist, returning a *WaitlistConflictError
+	// if an entry with its ID exists and an error if ValidateWaitlistEntry rejects it. Use
+	// NewWaitlistEntry to build e.
+	JoinWaitlist(ctx context.Context, e model.WaitlistEntry) error
+	// ListWaitlist returns the entries matching filter, ordered by SortWaitlist.
+	ListWaitlist(ctx context.Context, filter WaitlistFilter) ([]model.WaitlistEntry, error)
+	// OfferWaitlistEntry atomically records that a device is held for an entry. It returns
+	// a *WaitlistConflictError if the entry already holds one, so two devices coming back
+	// at once cannot both go to the same person, a *WaitlistEntryNotFoundError if there is
+	// no such entry, and an error if ValidateWaitlistOffer rejects offer. The hold itself is
+	// a reservation the caller creates first.
+	OfferWaitlistEntry(ctx context.Context, entryID string, offer model.WaitlistOffer) error
+	// LeaveWaitlist removes an entry, returning a *WaitlistEntryNotFoundError if there is
+	// none with the ID. It does not cancel the entry's hold.
+	LeaveWaitlist(ctx context.Context, entryID string) error
+
+	// History Operations
+	// Every write that changes a device appends a model.DeviceEvent attributed to the
+	// actor set with WithActor. ListDeviceEvents returns a device's events oldest first.
+	ListDeviceEvents(ctx context.Context, deviceID string) ([]model.DeviceEvent, error)
+	// IterateDeviceEvents calls fn for every recorded event, including those of deleted
+	// devices, in no particular order.
+	IterateDeviceEvents(ctx context.Context, fn func(model.DeviceEvent) error) error
 }
@@ -0,0 +1,403 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bdemetris/curator/pkg/database"
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+	"bdemetris/curator/pkg/store/storetest"
+)
+
+// The decorators must not change the behavior of the store they wrap.
+
+func TestCachingStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewCachingStore(newMemoryStore(t), time.Minute)
+	})
+}
+
+func TestRetryingStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewRetryingStore(newMemoryStore(t), store.DefaultRetryPolicy())
+	})
+}
+
+func TestValidatingStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewValidatingStore(newMemoryStore(t), model.DefaultSchemaRegistry())
+	})
+}
+
+func TestValidatingStoreRejectsInvalidAttributes(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewValidatingStore(newMemoryStore(t), model.DefaultSchemaRegistry())
+
+	monitor := model.Device{AssetTag: "M-1", DeviceType: "monitor", Attributes: map[string]string{"Size": " 27.0 "}}
+	if err := s.PutDevice(ctx, monitor); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+	got, err := s.GetDevice(ctx, "M-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if got.Attributes["size"] != "27" {
+		t.Errorf("size = %q, want it normalized to %q", got.Attributes["size"], "27")
+	}
+
+	invalid := map[string]store.DevicePatch{
+		"a non-numeric size":      {Attributes: map[string]store.Field[string]{"size": store.Set("large")}},
+		"an undefined attribute":  {Attributes: map[string]store.Field[string]{"carrier": store.Set("Verizon")}},
+		"a type without the size": {DeviceType: store.Set("Laptop")},
+	}
+	for name, patch := range invalid {
+		if err := s.UpdateDevice(ctx, "M-1", patch); !errors.Is(err, store.ErrInvalid) {
+			t.Errorf("UpdateDevice with %s: got %v, want store.ErrInvalid", name, err)
+		}
+	}
+
+	laptop := model.Device{AssetTag: "L-1", DeviceType: "Laptop", Attributes: map[string]string{"size": "13"}}
+	if err := s.BatchPutDevices(ctx, []model.Device{{AssetTag: "L-0"}, laptop}); !errors.Is(err, store.ErrInvalid) {
+		t.Errorf("BatchPutDevices with an invalid device: got %v, want store.ErrInvalid", err)
+	}
+	if _, err := s.GetDevice(ctx, "L-0"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("BatchPutDevices wrote devices before rejecting the batch")
+	}
+}
+
+func TestCachingStoreExpiresReads(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStore{Store: newMemoryStore(t)}
+	s := store.NewCachingStore(next, 50*time.Millisecond)
+	if err := next.PutDevice(ctx, model.Device{AssetTag: "C-1"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	for range 3 {
+		if _, err := s.GetDevice(ctx, "C-1"); err != nil {
+			t.Fatalf("GetDevice: %v", err)
+		}
+		if _, err := s.ListDevices(ctx); err != nil {
+			t.Fatalf("ListDevices: %v", err)
+		}
+	}
+	if next.gets != 1 || next.lists != 1 {
+		t.Errorf("reads within the TTL reached the wrapped store %d and %d times, want once each", next.gets, next.lists)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := s.GetDevice(ctx, "C-1"); err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if _, err := s.ListDevices(ctx); err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if next.gets != 2 || next.lists != 2 {
+		t.Errorf("reads after the TTL reached the wrapped store %d and %d times, want twice each", next.gets, next.lists)
+	}
+}
+
+func TestCachingStoreInvalidatesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStore{Store: newMemoryStore(t)}
+	s := store.NewCachingStore(next, time.Hour)
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "C-1", Location: "HQ", Attributes: map[string]string{"carrier": "AT&T"}}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+	if _, err := s.GetDevice(ctx, "C-1"); err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if _, err := s.ListDevices(ctx); err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+
+	if err := s.UpdateDevice(ctx, "C-1", store.DevicePatch{Location: store.Set("Lab")}); err != nil {
+		t.Fatalf("UpdateDevice: %v", err)
+	}
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "C-2"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	got, err := s.GetDevice(ctx, "C-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if got.Location != "Lab" {
+		t.Errorf("GetDevice after UpdateDevice: Location = %q, want %q", got.Location, "Lab")
+	}
+	devices, err := s.ListDevices(ctx)
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Errorf("ListDevices after PutDevice returned %d devices, want 2", len(devices))
+	}
+	if next.gets != 2 || next.lists != 2 {
+		t.Errorf("reads after writes reached the wrapped store %d and %d times, want twice each", next.gets, next.lists)
+	}
+
+	// Callers get copies, so changing one does not change the cache.
+	got.Attributes["carrier"] = "Verizon"
+	if again, _ := s.GetDevice(ctx, "C-1"); again.Attributes["carrier"] != "AT&T" {
+		t.Errorf("changing a returned device changed the cached one: %+v", again)
+	}
+}
+
+func TestCachingStoreDropsReadsRacingWrites(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStore{Store: newMemoryStore(t)}
+	s := store.NewCachingStore(next, time.Hour)
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "C-1", Location: "HQ"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	// The read gets the old device, then stalls until the write is done.
+	read, release := make(chan struct{}), make(chan struct{})
+	next.afterGet = func() {
+		close(read)
+		<-release
+	}
+	done := make(chan model.Device)
+	go func() {
+		device, _ := s.GetDevice(ctx, "C-1")
+		done <- device
+	}()
+
+	<-read
+	next.afterGet = nil
+	if err := s.UpdateDevice(ctx, "C-1", store.DevicePatch{Location: store.Set("Lab")}); err != nil {
+		t.Fatalf("UpdateDevice: %v", err)
+	}
+	close(release)
+	if stale := <-done; stale.Location != "HQ" {
+		t.Fatalf("the racing read returned Location %q, want the old %q", stale.Location, "HQ")
+	}
+
+	got, err := s.GetDevice(ctx, "C-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if got.Location != "Lab" {
+		t.Errorf("GetDevice after a racing read: Location = %q, want %q", got.Location, "Lab")
+	}
+}
+
+// fastRetries is a retry policy that does not slow tests down.
+var fastRetries = store.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetryingStoreRetriesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	next := newFlakyStore(t, store.Transient(errors.New("throttled")))
+	if err := next.PutDevice(ctx, model.Device{AssetTag: "R-1"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+	s := store.NewRetryingStore(next, fastRetries)
+
+	next.failures["GetDevice"] = 3
+	if _, err := s.GetDevice(ctx, "R-1"); err != nil {
+		t.Errorf("GetDevice failing 3 times out of 4 attempts: %v", err)
+	}
+	if next.calls["GetDevice"] != 4 {
+		t.Errorf("GetDevice was tried %d times, want 4", next.calls["GetDevice"])
+	}
+
+	next.calls["GetDevice"], next.failures["GetDevice"] = 0, 10
+	if _, err := s.GetDevice(ctx, "R-1"); !errors.Is(err, store.ErrTransient) {
+		t.Errorf("GetDevice failing every attempt: got %v, want the last error", err)
+	}
+	if next.calls["GetDevice"] != 4 {
+		t.Errorf("GetDevice was tried %d times, want MaxAttempts", next.calls["GetDevice"])
+	}
+}
+
+func TestRetryingStoreReturnsOtherErrors(t *testing.T) {
+	next := newFlakyStore(t, errors.New("access denied"))
+	next.failures["GetDevice"] = 1
+	s := store.NewRetryingStore(next, fastRetries)
+
+	if _, err := s.GetDevice(context.Background(), "R-1"); err == nil || errors.Is(err, store.ErrTransient) {
+		t.Errorf("GetDevice: got %v, want the error it failed with", err)
+	}
+	if next.calls["GetDevice"] != 1 {
+		t.Errorf("GetDevice was tried %d times after an error that is not retryable, want 1", next.calls["GetDevice"])
+	}
+}
+
+func TestRetryingStoreStopsAtTheDeadline(t *testing.T) {
+	next := newFlakyStore(t, store.Transient(errors.New("throttled")))
+	next.failures["GetDevice"] = 10
+	s := store.NewRetryingStore(next, store.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, MaxDelay: time.Second})
+
+	// The backoff may be up to a second, so it could outlast the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := s.GetDevice(ctx, "R-1"); !errors.Is(err, store.ErrTransient) {
+		t.Errorf("GetDevice: got %v, want the last error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("GetDevice took %s with a 50ms deadline", elapsed)
+	}
+}
+
+func TestRetryingStoreWaitsRetryAfter(t *testing.T) {
+	next := newFlakyStore(t, &store.TransientError{Err: errors.New("throttled"), RetryAfter: 50 * time.Millisecond})
+	next.failures["GetDevice"] = 1
+	s := store.NewRetryingStore(next, fastRetries)
+	if err := next.PutDevice(context.Background(), model.Device{AssetTag: "R-1"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := s.GetDevice(context.Background(), "R-1"); err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("GetDevice retried after %s, want at least the 50ms the backend asked for", elapsed)
+	}
+}
+
+func TestRetryingStoreAppliesWritesOnce(t *testing.T) {
+	ctx := context.Background()
+	next := newFlakyStore(t, store.Transient(errors.New("connection reset")))
+	s := store.NewRetryingStore(next, fastRetries)
+	now := time.Now()
+	if err := next.PutDevice(ctx, model.Device{AssetTag: "R-1"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+	if err := next.CheckoutDevice(ctx, "R-1", "ada@example.com", now, now.Add(time.Hour)); err != nil {
+		t.Fatalf("CheckoutDevice: %v", err)
+	}
+
+	// Each write goes through, but its response is lost.
+	next.failures["ReturnDevice"], next.failures["CreateReservation"] = 1, 1
+	next.applied = true
+
+	if err := s.ReturnDevice(ctx, "R-1", "ada@example.com"); err != nil {
+		t.Errorf("ReturnDevice that went through on the first attempt: %v", err)
+	}
+	events, err := next.ListDeviceEvents(ctx, "R-1")
+	if err != nil {
+		t.Fatalf("ListDeviceEvents: %v", err)
+	}
+	if last := events[len(events)-1]; len(events) != 3 || last.Type != model.DeviceReturned {
+		t.Errorf("history after a retried return = %d events ending in %s, want one return", len(events), last.Type)
+	}
+
+	r := store.NewReservation("R-1", "ada@example.com", now.Add(24*time.Hour), now.Add(48*time.Hour))
+	if err := s.CreateReservation(ctx, r); err != nil {
+		t.Errorf("CreateReservation that went through on the first attempt: %v", err)
+	}
+	if next.calls["CreateReservation"] != 1 {
+		t.Errorf("CreateReservation was run %d times, want once", next.calls["CreateReservation"])
+	}
+}
+
+func TestRetryingStoreReportsConflictsOnRetry(t *testing.T) {
+	ctx := context.Background()
+	next := newFlakyStore(t, store.Transient(errors.New("connection reset")))
+	s := store.NewRetryingStore(next, fastRetries)
+	now := time.Now()
+	if err := next.PutDevice(ctx, model.Device{AssetTag: "R-1"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+	if err := next.CheckoutDevice(ctx, "R-1", "ada@example.com", now, now.Add(time.Hour)); err != nil {
+		t.Fatalf("CheckoutDevice: %v", err)
+	}
+
+	// The first attempt fails, and someone else returns the device before the retry.
+	next.failures["ReturnDevice"] = 1
+	next.afterFailure = func() {
+		next.afterFailure = nil
+		if err := next.Store.ReturnDevice(ctx, "R-1", "ada@example.com"); err != nil {
+			t.Fatalf("ReturnDevice: %v", err)
+		}
+	}
+
+	if err := s.ReturnDevice(ctx, "R-1", "ada@example.com"); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("ReturnDevice of a device returned by someone else: got %v, want store.ErrConflict", err)
+	}
+}
+
+// flakyStore fails the first failures[name] calls to each overridden method with err, then
+// passes calls through to the store it wraps. If applied is set, failed calls still reach
+// the wrapped store, as when only the response is lost. afterFailure, if set, runs after
+// each failed call.
+type flakyStore struct {
+	store.Store
+	err          error
+	applied      bool
+	failures     map[string]int
+	calls        map[string]int
+	afterFailure func()
+}
+
+func newFlakyStore(t *testing.T, err error) *flakyStore {
+	return &flakyStore{Store: newMemoryStore(t), err: err, failures: make(map[string]int), calls: make(map[string]int)}
+}
+
+func (f *flakyStore) call(name string, op func() error) error {
+	f.calls[name]++
+	if f.calls[name] > f.failures[name] {
+		return op()
+	}
+	if f.applied {
+		if err := op(); err != nil {
+			return err
+		}
+	}
+	if f.afterFailure != nil {
+		f.afterFailure()
+	}
+	return f.err
+}
+
+func (f *flakyStore) GetDevice(ctx context.Context, deviceID string) (model.Device, error) {
+	var device model.Device
+	err := f.call("GetDevice", func() (err error) {
+		device, err = f.Store.GetDevice(ctx, deviceID)
+		return err
+	})
+	return device, err
+}
+
+func (f *flakyStore) ReturnDevice(ctx context.Context, deviceID, assignee string) error {
+	return f.call("ReturnDevice", func() error { return f.Store.ReturnDevice(ctx, deviceID, assignee) })
+}
+
+func (f *flakyStore) CreateReservation(ctx context.Context, r model.Reservation) error {
+	return f.call("CreateReservation", func() error { return f.Store.CreateReservation(ctx, r) })
+}
+
+// countingStore counts the device reads that reach the store it wraps. afterGet, if set,
+// runs after each GetDevice has read the device.
+type countingStore struct {
+	store.Store
+	gets, lists int
+	afterGet    func()
+}
+
+func (c *countingStore) GetDevice(ctx context.Context, deviceID string) (model.Device, error) {
+	c.gets++
+	device, err := c.Store.GetDevice(ctx, deviceID)
+	if c.afterGet != nil {
+		c.afterGet()
+	}
+	return device, err
+}
+
+func (c *countingStore) ListDevices(ctx context.Context) ([]model.Device, error) {
+	c.lists++
+	return c.Store.ListDevices(ctx)
+}
+
+func newMemoryStore(t *testing.T) store.Store {
+	s, err := database.NewMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewMemoryStore: %v", err)
+	}
+	return s
+}
@@ -0,0 +1,1395 @@
+// Package storetest checks that a store.Store implementation behaves the way the rest of
+// curator expects, so that providers stay interchangeable behind store.NewStoreFactory.
+//
+// A provider's tests call Run with a Factory that returns a new, empty store:
+//
+//	func TestMemoryStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store {
+//			s, err := database.NewMemoryStore("")
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return s
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+	"bdemetris/curator/pkg/store"
+)
+
+// Factory returns a new, empty store for a single test. It should register any cleanup
+// with t.Cleanup; Run closes the store itself.
+type Factory func(t *testing.T) store.Store
+
+// Run runs the conformance suite against the stores returned by newStore, one store per
+// subtest.
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"EmptyStore", testEmptyStore},
+		{"NotFound", testNotFound},
+		{"PutGetRoundTrip", testPutGetRoundTrip},
+		{"PutReplaces", testPutReplaces},
+		{"PartialUpdate", testPartialUpdate},
+		{"UpdateClearsFields", testUpdateClearsFields},
+		{"UpdateEveryField", testUpdateEveryField},
+		{"InvalidUpdate", testInvalidUpdate},
+		{"ListAndIterate", testListAndIterate},
+		{"QueryDevices", testQueryDevices},
+		{"CustomAttributes", testCustomAttributes},
+		{"BatchPutAndGet", testBatchPutAndGet},
+		{"CheckoutAndReturn", testCheckoutAndReturn},
+		{"ConcurrentCheckouts", testConcurrentCheckouts},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+		{"StatusTransitions", testStatusTransitions},
+		{"RetireAndDelete", testRetireAndDelete},
+		{"Reservations", testReservations},
+		{"Waitlist", testWaitlist},
+		{"History", testHistory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			t.Cleanup(func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+			tt.fn(t, s)
+		})
+	}
+}
+
+// at returns a timestamp with whole seconds in a zone other than UTC. Providers are only
+// required to keep timestamps to the second, and may return them in any zone.
+func at(day, hour int) *time.Time {
+	t := time.Date(2024, time.March, day, hour, 30, 15, 0, time.FixedZone("UTC-7", -7*60*60))
+	return &t
+}
+
+func testEmptyStore(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	devices, err := s.ListDevices(ctx)
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if len(devices) != 0 {
+		t.Errorf("ListDevices on an empty store returned %d devices", len(devices))
+	}
+
+	err = s.IterateDevices(ctx, func(d model.Device) error {
+		t.Errorf("IterateDevices on an empty store passed %s", d.AssetTag)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("IterateDevices: %v", err)
+	}
+
+	devices, err = s.QueryDevices(ctx, store.DeviceFilter{Availability: store.OnlyAvailable})
+	if err != nil {
+		t.Fatalf("QueryDevices: %v", err)
+	}
+	if len(devices) != 0 {
+		t.Errorf("QueryDevices on an empty store returned %d devices", len(devices))
+	}
+
+	devices, err = s.BatchGetDevices(ctx, []string{"missing-1", "missing-2"})
+	if err != nil {
+		t.Fatalf("BatchGetDevices: %v", err)
+	}
+	if len(devices) != 0 {
+		t.Errorf("BatchGetDevices of unknown tags returned %d devices", len(devices))
+	}
+
+	if err := s.BatchPutDevices(ctx, nil); err != nil {
+		t.Errorf("BatchPutDevices with no devices: %v", err)
+	}
+	devices, err = s.BatchGetDevices(ctx, nil)
+	if err != nil {
+		t.Fatalf("BatchGetDevices with no tags: %v", err)
+	}
+	if len(devices) != 0 {
+		t.Errorf("BatchGetDevices with no tags returned %d devices", len(devices))
+	}
+
+	events, err := s.ListDeviceEvents(ctx, "missing")
+	if err != nil {
+		t.Fatalf("ListDeviceEvents: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("ListDeviceEvents of an unknown device returned %d events", len(events))
+	}
+}
+
+func testNotFound(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	calls := map[string]func() error{
+		"GetDevice": func() error {
+			_, err := s.GetDevice(ctx, "missing")
+			return err
+		},
+		"UpdateDevice": func() error {
+			return s.UpdateDevice(ctx, "missing", store.DevicePatch{Location: store.Set("Lab")})
+		},
+		"CheckoutDevice": func() error {
+			return s.CheckoutDevice(ctx, "missing", "ada@example.com", now, now.AddDate(0, 0, 30))
+		},
+		"ReturnDevice": func() error {
+			return s.ReturnDevice(ctx, "missing", "ada@example.com")
+		},
+		"SetDeviceStatus": func() error {
+			return s.SetDeviceStatus(ctx, "missing", model.StatusInRepair)
+		},
+		"DeleteDevice": func() error {
+			return s.DeleteDevice(ctx, "missing")
+		},
+	}
+
+	for name, call := range calls {
+		err := call()
+		if !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s of an unknown device: got %v, want an error matching store.ErrNotFound", name, err)
+			continue
+		}
+		var notFound *store.NotFoundError
+		if !errors.As(err, &notFound) || notFound.AssetTag != "missing" {
+			t.Errorf("%s of an unknown device: got %v, want a *store.NotFoundError for missing", name, err)
+		}
+	}
+
+	// A failed update must not create the device.
+	if _, err := s.GetDevice(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetDevice after failed writes: got %v, want store.ErrNotFound", err)
+	}
+}
+
+func testPutGetRoundTrip(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	full := model.Device{
+		AssetTag:     "RT-1",
+		DeviceType:   "Laptop",
+		DeviceMake:   "Apple",
+		DeviceModel:  "MacBook Pro",
+		Location:     "HQ",
+		AssignedTo:   "ada@example.com",
+		AssignedDate: at(1, 9),
+		DueDate:      at(31, 17),
+
+		SerialNumber:       "C02XK1JHJG5J",
+		Vendor:             "Apple Business",
+		PurchaseDate:       at(1, 12),
+		PurchaseCost:       249900,
+		WarrantyExpiration: at(365, 0),
+	}
+	bare := model.Device{AssetTag: "RT-2", DeviceType: "Phone"}
+
+	for _, d := range []model.Device{full, bare} {
+		if err := s.PutDevice(ctx, d); err != nil {
+			t.Fatalf("PutDevice %s: %v", d.AssetTag, err)
+		}
+	}
+
+	for _, want := range []model.Device{full, bare} {
+		got, err := s.GetDevice(ctx, want.AssetTag)
+		if err != nil {
+			t.Fatalf("GetDevice %s: %v", want.AssetTag, err)
+		}
+		checkDevice(t, "GetDevice", got, want)
+	}
+}
+
+func testPutReplaces(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	first := model.Device{
+		AssetTag:     "PR-1",
+		DeviceType:   "Laptop",
+		DeviceModel:  "ThinkPad",
+		Location:     "HQ",
+		AssignedTo:   "ada@example.com",
+		AssignedDate: at(1, 9),
+		DueDate:      at(31, 17),
+	}
+	second := model.Device{AssetTag: "PR-1", DeviceType: "Laptop", DeviceModel: "ThinkPad X1", Location: "Lab"}
+
+	if err := s.PutDevice(ctx, first); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+	if err := s.PutDevice(ctx, second); err != nil {
+		t.Fatalf("PutDevice again: %v", err)
+	}
+
+	got, err := s.GetDevice(ctx, "PR-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after replacing", got, second)
+
+	devices, err := s.ListDevices(ctx)
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Errorf("ListDevices after replacing a device returned %d devices, want 1", len(devices))
+	}
+}
+
+func testPartialUpdate(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	device := model.Device{
+		AssetTag:     "PU-1",
+		DeviceType:   "Tablet",
+		DeviceMake:   "Apple",
+		DeviceModel:  "iPad",
+		Location:     "HQ",
+		AssignedTo:   "ada@example.com",
+		AssignedDate: at(1, 9),
+		DueDate:      at(31, 17),
+	}
+	if err := s.PutDevice(ctx, device); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	if err := s.UpdateDevice(ctx, "PU-1", store.DevicePatch{Location: store.Set("Lab")}); err != nil {
+		t.Fatalf("UpdateDevice Location: %v", err)
+	}
+	device.Location = "Lab"
+	got, err := s.GetDevice(ctx, "PU-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after updating Location", got, device)
+
+	if err := s.UpdateDevice(ctx, "PU-1", store.DevicePatch{WarrantyExpiration: store.Set(*at(20, 12)), DeviceModel: store.Set("iPad Air")}); err != nil {
+		t.Fatalf("UpdateDevice WarrantyExpiration and DeviceModel: %v", err)
+	}
+	device.WarrantyExpiration = at(20, 12)
+	device.DeviceModel = "iPad Air"
+	got, err = s.GetDevice(ctx, "PU-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after updating WarrantyExpiration and DeviceModel", got, device)
+}
+
+func testUpdateClearsFields(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	device := model.Device{
+		AssetTag:     "UC-1",
+		DeviceType:   "Laptop",
+		Location:     "HQ",
+		AssignedTo:   "ada@example.com",
+		AssignedDate: at(1, 9),
+		DueDate:      at(31, 17),
+
+		Vendor:             "Framework",
+		PurchaseCost:       109950,
+		WarrantyExpiration: at(365, 0),
+	}
+	if err := s.PutDevice(ctx, device); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	err := s.UpdateDevice(ctx, "UC-1", store.DevicePatch{
+		Location:           store.Clear[string](),
+		PurchaseCost:       store.Clear[int64](),
+		WarrantyExpiration: store.Clear[time.Time](),
+	})
+	if err != nil {
+		t.Fatalf("UpdateDevice: %v", err)
+	}
+
+	// The assignment is untouched: only checkouts and returns change it.
+	device.Location, device.PurchaseCost, device.WarrantyExpiration = "", 0, nil
+	got, err := s.GetDevice(ctx, "UC-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after clearing fields", got, device)
+}
+
+func testUpdateEveryField(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "UE-1"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	err := s.UpdateDevice(ctx, "UE-1", store.DevicePatch{
+		DeviceType:  store.Set("Laptop"),
+		DeviceMake:  store.Set("Framework"),
+		DeviceModel: store.Set("Laptop 13"),
+		Location:    store.Set("HQ"),
+
+		SerialNumber:       store.Set("FW13-0042"),
+		Vendor:             store.Set("Framework"),
+		PurchaseDate:       store.Set(*at(1, 12)),
+		PurchaseCost:       store.Set(int64(109950)),
+		WarrantyExpiration: store.Set(*at(365, 0)),
+	})
+	if err != nil {
+		t.Fatalf("UpdateDevice: %v", err)
+	}
+
+	want := model.Device{
+		AssetTag:    "UE-1",
+		DeviceType:  "Laptop",
+		DeviceMake:  "Framework",
+		DeviceModel: "Laptop 13",
+		Location:    "HQ",
+
+		SerialNumber:       "FW13-0042",
+		Vendor:             "Framework",
+		PurchaseDate:       at(1, 12),
+		PurchaseCost:       109950,
+		WarrantyExpiration: at(365, 0),
+	}
+	got, err := s.GetDevice(ctx, "UE-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after setting every field", got, want)
+
+	err = s.UpdateDevice(ctx, "UE-1", store.DevicePatch{
+		DeviceType:  store.Clear[string](),
+		DeviceMake:  store.Clear[string](),
+		DeviceModel: store.Clear[string](),
+		Location:    store.Clear[string](),
+
+		SerialNumber:       store.Clear[string](),
+		Vendor:             store.Clear[string](),
+		PurchaseDate:       store.Clear[time.Time](),
+		PurchaseCost:       store.Clear[int64](),
+		WarrantyExpiration: store.Clear[time.Time](),
+	})
+	if err != nil {
+		t.Fatalf("UpdateDevice: %v", err)
+	}
+
+	got, err = s.GetDevice(ctx, "UE-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after clearing every field", got, model.Device{AssetTag: "UE-1"})
+}
+
+func testInvalidUpdate(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	device := model.Device{AssetTag: "IU-1", DeviceType: "Laptop", Location: "HQ"}
+	if err := s.PutDevice(ctx, device); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	patches := map[string]store.DevicePatch{
+		"an empty patch":         {},
+		"an empty string":        {Location: store.Set("")},
+		"a zero time":            {PurchaseDate: store.Set(time.Time{})},
+		"a zero cost":            {PurchaseCost: store.Set(int64(0))},
+		"a negative cost":        {PurchaseCost: store.Set(int64(-100))},
+		"a valid and bad change": {DeviceType: store.Set("Phone"), Vendor: store.Set("")},
+	}
+	for name, patch := range patches {
+		if err := s.UpdateDevice(ctx, "IU-1", patch); err == nil {
+			t.Errorf("UpdateDevice with %s succeeded, want an error", name)
+		}
+	}
+
+	got, err := s.GetDevice(ctx, "IU-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after invalid updates", got, device)
+}
+
+func testListAndIterate(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	want := putDevices(t, s, 7)
+
+	devices, err := s.ListDevices(ctx)
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	checkDeviceSet(t, "ListDevices", devices, want)
+
+	var iterated []model.Device
+	err = s.IterateDevices(ctx, func(d model.Device) error {
+		iterated = append(iterated, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("IterateDevices: %v", err)
+	}
+	checkDeviceSet(t, "IterateDevices", iterated, want)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = s.IterateDevices(ctx, func(d model.Device) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("IterateDevices returned %v, want the error returned by fn", err)
+	}
+	if calls != 1 {
+		t.Errorf("IterateDevices called fn %d times after it failed, want 1", calls)
+	}
+}
+
+func testQueryDevices(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	devices := []model.Device{
+		{AssetTag: "Q-1", DeviceType: "Laptop", Location: "HQ", WarrantyExpiration: at(10, 0)},
+		{AssetTag: "Q-2", DeviceType: "Laptop", Location: "Lab", WarrantyExpiration: at(40, 0)},
+		{AssetTag: "Q-3", DeviceType: "Phone", Location: "HQ"},
+		{AssetTag: "Q-4", DeviceType: "Phone", Location: "Lab"},
+	}
+	for _, d := range devices {
+		if err := s.PutDevice(ctx, d); err != nil {
+			t.Fatalf("PutDevice %s: %v", d.AssetTag, err)
+		}
+	}
+	if err := s.CheckoutDevice(ctx, "Q-1", "ada@example.com", now.AddDate(0, 0, -40), now.AddDate(0, 0, -10)); err != nil {
+		t.Fatalf("CheckoutDevice Q-1: %v", err)
+	}
+	if err := s.CheckoutDevice(ctx, "Q-3", "Grace@Example.com", now, now.AddDate(0, 0, 30)); err != nil {
+		t.Fatalf("CheckoutDevice Q-3: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter store.DeviceFilter
+		want   []string
+	}{
+		{"everything", store.DeviceFilter{}, []string{"Q-1", "Q-2", "Q-3", "Q-4"}},
+		{"assignee", store.DeviceFilter{AssignedTo: "ada@example.com"}, []string{"Q-1"}},
+		{"assignee in another case", store.DeviceFilter{AssignedTo: "Ada@Example.com"}, []string{"Q-1"}},
+		{"assignee stored in another case", store.DeviceFilter{AssignedTo: "grace@example.com"}, []string{"Q-3"}},
+		{"type", store.DeviceFilter{DeviceType: "phone"}, []string{"Q-3", "Q-4"}},
+		{"type and location", store.DeviceFilter{DeviceType: "Laptop", Location: "lab"}, []string{"Q-2"}},
+		{"available", store.DeviceFilter{Availability: store.OnlyAvailable}, []string{"Q-2", "Q-4"}},
+		{"checked out", store.DeviceFilter{Availability: store.OnlyCheckedOut}, []string{"Q-1", "Q-3"}},
+		{"overdue", store.DeviceFilter{Availability: store.OnlyCheckedOut, DueBefore: &now}, []string{"Q-1"}},
+		{"warranty expiring", store.DeviceFilter{WarrantyBefore: at(30, 0)}, []string{"Q-1"}},
+		{"no match", store.DeviceFilter{DeviceType: "Monitor"}, nil},
+	}
+
+	for _, tt := range tests {
+		got, err := s.QueryDevices(ctx, tt.filter)
+		if err != nil {
+			t.Errorf("QueryDevices (%s): %v", tt.name, err)
+			continue
+		}
+		checkTags(t, fmt.Sprintf("QueryDevices (%s)", tt.name), got, tt.want)
+	}
+}
+
+// testCustomAttributes uses attributes that the default schema registry defines, so that
+// the suite also passes behind a store.ValidatingStore.
+func testCustomAttributes(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	devices := []model.Device{
+		{AssetTag: "CA-1", DeviceType: "Phone", Attributes: map[string]string{"carrier": "Verizon", "os_version": "17.4"}},
+		{AssetTag: "CA-2", DeviceType: "Phone", Attributes: map[string]string{"carrier": "T-Mobile"}},
+		{AssetTag: "CA-3", DeviceType: "Phone"},
+	}
+	for _, d := range devices {
+		if err := s.PutDevice(ctx, d); err != nil {
+			t.Fatalf("PutDevice %s: %v", d.AssetTag, err)
+		}
+	}
+	got, err := s.GetDevice(ctx, "CA-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice", got, devices[0])
+
+	err = s.UpdateDevice(ctx, "CA-1", store.DevicePatch{Attributes: map[string]store.Field[string]{
+		"carrier":    store.Set("AT&T"),
+		"os_version": store.Clear[string](),
+	}})
+	if err != nil {
+		t.Fatalf("UpdateDevice CA-1: %v", err)
+	}
+	err = s.UpdateDevice(ctx, "CA-2", store.DevicePatch{Attributes: map[string]store.Field[string]{
+		"carrier": store.Clear[string](),
+	}})
+	if err != nil {
+		t.Fatalf("UpdateDevice CA-2: %v", err)
+	}
+	err = s.UpdateDevice(ctx, "CA-3", store.DevicePatch{
+		Location:   store.Set("HQ"),
+		Attributes: map[string]store.Field[string]{"os_version": store.Set("14")},
+	})
+	if err != nil {
+		t.Fatalf("UpdateDevice CA-3: %v", err)
+	}
+
+	want := map[string]model.Device{
+		"CA-1": {AssetTag: "CA-1", DeviceType: "Phone", Attributes: map[string]string{"carrier": "AT&T"}},
+		"CA-2": {AssetTag: "CA-2", DeviceType: "Phone"},
+		"CA-3": {AssetTag: "CA-3", DeviceType: "Phone", Location: "HQ", Attributes: map[string]string{"os_version": "14"}},
+	}
+	for tag, device := range want {
+		got, err := s.GetDevice(ctx, tag)
+		if err != nil {
+			t.Fatalf("GetDevice %s: %v", tag, err)
+		}
+		checkDevice(t, "GetDevice after updating attributes", got, device)
+	}
+
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  []string
+	}{
+		{"value", map[string]string{"carrier": "AT&T"}, []string{"CA-1"}},
+		{"value in another case", map[string]string{"Carrier": "at&t"}, []string{"CA-1"}},
+		{"cleared value", map[string]string{"carrier": "T-Mobile"}, nil},
+		{"two values", map[string]string{"carrier": "AT&T", "os_version": "14"}, nil},
+	}
+	for _, tt := range tests {
+		got, err := s.QueryDevices(ctx, store.DeviceFilter{Attributes: tt.attrs})
+		if err != nil {
+			t.Errorf("QueryDevices (%s): %v", tt.name, err)
+			continue
+		}
+		checkTags(t, fmt.Sprintf("QueryDevices (%s)", tt.name), got, tt.want)
+	}
+}
+
+func testBatchPutAndGet(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	devices := []model.Device{
+		{AssetTag: "B-1", DeviceType: "Laptop", AssignedTo: "ada@example.com", AssignedDate: at(1, 9), DueDate: at(31, 17)},
+		{AssetTag: "B-2", DeviceType: "Phone"},
+		{AssetTag: "B-3", DeviceType: "Tablet"},
+		{AssetTag: "B-2", DeviceType: "Phone", Location: "Lab"}, // The last write of a tag wins
+	}
+	if err := s.BatchPutDevices(ctx, devices); err != nil {
+		t.Fatalf("BatchPutDevices: %v", err)
+	}
+
+	got, err := s.BatchGetDevices(ctx, []string{"B-3", "missing", "B-1", "B-3", "B-2"})
+	if err != nil {
+		t.Fatalf("BatchGetDevices: %v", err)
+	}
+	checkTagOrder(t, "BatchGetDevices", got, []string{"B-3", "B-1", "B-2"})
+	if len(got) == 3 {
+		checkDevice(t, "BatchGetDevices", got[0], devices[2])
+		checkDevice(t, "BatchGetDevices", got[1], devices[0])
+		checkDevice(t, "BatchGetDevices", got[2], devices[3])
+	}
+}
+
+func testCheckoutAndReturn(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "C-1", DeviceType: "Laptop", Location: "HQ"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	assigned, due := at(1, 9), at(31, 17)
+	if err := s.CheckoutDevice(ctx, "C-1", "ada@example.com", *assigned, *due); err != nil {
+		t.Fatalf("CheckoutDevice: %v", err)
+	}
+	want := model.Device{AssetTag: "C-1", DeviceType: "Laptop", Location: "HQ", AssignedTo: "ada@example.com", AssignedDate: assigned, DueDate: due}
+	got, err := s.GetDevice(ctx, "C-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after checkout", got, want)
+
+	// Checking out again to the same assignee renews the loan.
+	renewed := at(31, 18)
+	if err := s.CheckoutDevice(ctx, "C-1", "ada@example.com", *assigned, *renewed); err != nil {
+		t.Fatalf("CheckoutDevice by the same assignee: %v", err)
+	}
+
+	err = s.CheckoutDevice(ctx, "C-1", "grace@example.com", *assigned, *due)
+	var conflict *store.ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("CheckoutDevice by someone else: got %v, want a *store.ConflictError", err)
+	}
+	if conflict.AssignedTo != "ada@example.com" {
+		t.Errorf("ConflictError.AssignedTo = %q, want ada@example.com", conflict.AssignedTo)
+	}
+	if conflict.DueDate != nil && !conflict.DueDate.Equal(*renewed) {
+		t.Errorf("ConflictError.DueDate = %v, want %v", conflict.DueDate, renewed)
+	}
+
+	err = s.ReturnDevice(ctx, "C-1", "grace@example.com")
+	if !errors.As(err, &conflict) || conflict.AssignedTo != "ada@example.com" {
+		t.Errorf("ReturnDevice by someone else: got %v, want a *store.ConflictError naming ada@example.com", err)
+	}
+
+	if err := s.ReturnDevice(ctx, "C-1", "ada@example.com"); err != nil {
+		t.Fatalf("ReturnDevice: %v", err)
+	}
+	want.AssignedTo, want.AssignedDate, want.DueDate = "", nil, nil
+	got, err = s.GetDevice(ctx, "C-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after return", got, want)
+
+	err = s.ReturnDevice(ctx, "C-1", "ada@example.com")
+	if !errors.As(err, &conflict) || conflict.AssignedTo != "" {
+		t.Errorf("ReturnDevice of an available device: got %v, want a *store.ConflictError with no assignee", err)
+	}
+}
+
+func testConcurrentCheckouts(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	const workers = 8
+
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "CC-1", DeviceType: "Laptop"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	now := time.Now()
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.CheckoutDevice(ctx, "CC-1", fmt.Sprintf("user%d@example.com", i), now, now.AddDate(0, 0, 30))
+		}()
+	}
+	wg.Wait()
+
+	winner := ""
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if winner != "" {
+				t.Errorf("both %s and user%d@example.com checked out the device", winner, i)
+			}
+			winner = fmt.Sprintf("user%d@example.com", i)
+		case !errors.Is(err, store.ErrConflict):
+			t.Errorf("CheckoutDevice by user%d@example.com: got %v, want success or store.ErrConflict", i, err)
+		}
+	}
+	if winner == "" {
+		t.Fatal("no concurrent checkout succeeded")
+	}
+
+	got, err := s.GetDevice(ctx, "CC-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if got.AssignedTo != winner {
+		t.Errorf("device is assigned to %q, want the successful caller %s", got.AssignedTo, winner)
+	}
+}
+
+func testConcurrentUpdates(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	device := model.Device{AssetTag: "CU-1", DeviceType: "Laptop", DeviceMake: "Dell", DeviceModel: "XPS", Location: "HQ"}
+	if err := s.PutDevice(ctx, device); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	// Concurrent updates of different fields must not overwrite each other.
+	updates := []store.DevicePatch{
+		{DeviceType: store.Set("Workstation")},
+		{DeviceMake: store.Set("Lenovo")},
+		{DeviceModel: store.Set("P16")},
+		{Location: store.Set("Lab")},
+	}
+	errs := make([]error, len(updates))
+	var wg sync.WaitGroup
+	for i, update := range updates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.UpdateDevice(ctx, "CU-1", update)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("UpdateDevice %v: %v", updates[i].Fields(), err)
+		}
+	}
+
+	want := model.Device{AssetTag: "CU-1", DeviceType: "Workstation", DeviceMake: "Lenovo", DeviceModel: "P16", Location: "Lab"}
+	got, err := s.GetDevice(ctx, "CU-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after concurrent updates", got, want)
+}
+
+func testStatusTransitions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "ST-1", DeviceType: "Laptop"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "ST-2", DeviceType: "Laptop"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+
+	if err := s.SetDeviceStatus(ctx, "ST-1", model.StatusInRepair); err != nil {
+		if errors.Is(err, store.ErrUnsupported) {
+			t.Skipf("SetDeviceStatus is not supported: %v", err)
+		}
+		t.Fatalf("SetDeviceStatus: %v", err)
+	}
+	got, err := s.GetDevice(ctx, "ST-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after sending to repair", got, model.Device{AssetTag: "ST-1", DeviceType: "Laptop", Status: model.StatusInRepair})
+
+	err = s.CheckoutDevice(ctx, "ST-1", "ada@example.com", now, now.AddDate(0, 0, 30))
+	checkTransitionError(t, "CheckoutDevice of a device in repair", err, model.StatusInRepair, model.StatusCheckedOut)
+
+	available, err := s.QueryDevices(ctx, store.DeviceFilter{Availability: store.OnlyAvailable})
+	if err != nil {
+		t.Fatalf("QueryDevices: %v", err)
+	}
+	checkTags(t, "QueryDevices of available devices", available, []string{"ST-2"})
+	inRepair, err := s.QueryDevices(ctx, store.DeviceFilter{Status: model.StatusInRepair})
+	if err != nil {
+		t.Fatalf("QueryDevices: %v", err)
+	}
+	checkTags(t, "QueryDevices of devices in repair", inRepair, []string{"ST-1"})
+
+	// A checked-out device that goes missing is no longer overdue, but remembers who had it.
+	if err := s.CheckoutDevice(ctx, "ST-2", "ada@example.com", *at(1, 9), *at(2, 9)); err != nil {
+		t.Fatalf("CheckoutDevice: %v", err)
+	}
+	if err := s.SetDeviceStatus(ctx, "ST-2", model.StatusLost); err != nil {
+		t.Fatalf("SetDeviceStatus: %v", err)
+	}
+	got, err = s.GetDevice(ctx, "ST-2")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after losing a checked-out device", got, model.Device{
+		AssetTag: "ST-2", DeviceType: "Laptop", Status: model.StatusLost,
+		AssignedTo: "ada@example.com", AssignedDate: at(1, 9), DueDate: at(2, 9),
+	})
+	overdue, err := s.QueryDevices(ctx, store.DeviceFilter{Availability: store.OnlyCheckedOut, DueBefore: &now})
+	if err != nil {
+		t.Fatalf("QueryDevices: %v", err)
+	}
+	checkTags(t, "QueryDevices of overdue devices", overdue, nil)
+	lost, err := s.QueryDevices(ctx, store.DeviceFilter{AssignedTo: "ada@example.com", Status: model.StatusLost})
+	if err != nil {
+		t.Fatalf("QueryDevices: %v", err)
+	}
+	checkTags(t, "QueryDevices of devices lost by their assignee", lost, []string{"ST-2"})
+
+	err = s.SetDeviceStatus(ctx, "ST-2", model.StatusInRepair)
+	checkTransitionError(t, "SetDeviceStatus from lost to in repair", err, model.StatusLost, model.StatusInRepair)
+	if err := s.SetDeviceStatus(ctx, "ST-2", model.StatusCheckedOut); err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("SetDeviceStatus to checked out: got %v, want a validation error", err)
+	}
+	if err := s.SetDeviceStatus(ctx, "ST-2", model.DeviceStatus("reserved")); err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("SetDeviceStatus to an unknown status: got %v, want a validation error", err)
+	}
+
+	if err := s.SetDeviceStatus(ctx, "ST-2", model.StatusAvailable); err != nil {
+		t.Fatalf("SetDeviceStatus: %v", err)
+	}
+	got, err = s.GetDevice(ctx, "ST-2")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	checkDevice(t, "GetDevice after finding a lost device", got, model.Device{AssetTag: "ST-2", DeviceType: "Laptop", Status: model.StatusAvailable})
+	if err := s.CheckoutDevice(ctx, "ST-2", "ada@example.com", now, now.AddDate(0, 0, 30)); err != nil {
+		t.Fatalf("CheckoutDevice of a found device: %v", err)
+	}
+	got, err = s.GetDevice(ctx, "ST-2")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if got.CurrentStatus() != model.StatusCheckedOut {
+		t.Errorf("status after CheckoutDevice = %q, want %q", got.CurrentStatus(), model.StatusCheckedOut)
+	}
+	if err := s.ReturnDevice(ctx, "ST-2", "ada@example.com"); err != nil {
+		t.Fatalf("ReturnDevice: %v", err)
+	}
+	got, err = s.GetDevice(ctx, "ST-2")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if got.CurrentStatus() != model.StatusAvailable {
+		t.Errorf("status after ReturnDevice = %q, want %q", got.CurrentStatus(), model.StatusAvailable)
+	}
+}
+
+func testRetireAndDelete(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, d := range []model.Device{
+		{AssetTag: "RD-1", DeviceType: "Laptop"},
+		{AssetTag: "RD-2", DeviceType: "Laptop"},
+		{AssetTag: "RD-3", DeviceType: "Laptop"},
+	} {
+		if err := s.PutDevice(ctx, d); err != nil {
+			t.Fatalf("PutDevice %s: %v", d.AssetTag, err)
+		}
+	}
+	if err := s.CheckoutDevice(ctx, "RD-1", "ada@example.com", now, now.AddDate(0, 0, 30)); err != nil {
+		t.Fatalf("CheckoutDevice: %v", err)
+	}
+
+	if err := s.SetDeviceStatus(ctx, "RD-1", model.StatusRetired); err != nil {
+		if errors.Is(err, store.ErrUnsupported) {
+			t.Skipf("SetDeviceStatus is not supported: %v", err)
+		}
+		t.Fatalf("SetDeviceStatus: %v", err)
+	}
+	got, err := s.GetDevice(ctx, "RD-1")
+	if err != nil {
+		t.Fatalf("GetDevice of a retired device: %v", err)
+	}
+	checkDevice(t, "GetDevice after retiring", got, model.Device{AssetTag: "RD-1", DeviceType: "Laptop", Status: model.StatusRetired})
+
+	err = s.CheckoutDevice(ctx, "RD-1", "ada@example.com", now, now.AddDate(0, 0, 30))
+	checkTransitionError(t, "CheckoutDevice of a retired device", err, model.StatusRetired, model.StatusCheckedOut)
+	err = s.SetDeviceStatus(ctx, "RD-1", model.StatusAvailable)
+	checkTransitionError(t, "SetDeviceStatus of a retired device", err, model.StatusRetired, model.StatusAvailable)
+
+	active, err := s.QueryDevices(ctx, store.DeviceFilter{})
+	if err != nil {
+		t.Fatalf("QueryDevices: %v", err)
+	}
+	checkTags(t, "QueryDevices without retired devices", active, []string{"RD-2", "RD-3"})
+	all, err := s.QueryDevices(ctx, store.DeviceFilter{IncludeRetired: true})
+	if err != nil {
+		t.Fatalf("QueryDevices: %v", err)
+	}
+	checkTags(t, "QueryDevices with retired devices", all, []string{"RD-1", "RD-2", "RD-3"})
+
+	err = s.DeleteDevice(ctx, "RD-2")
+	if errors.Is(err, store.ErrUnsupported) {
+		t.Skip("provider cannot delete devices")
+	}
+	if err != nil {
+		t.Fatalf("DeleteDevice: %v", err)
+	}
+	if _, err := s.GetDevice(ctx, "RD-2"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetDevice of a deleted device: got %v, want store.ErrNotFound", err)
+	}
+	if err := s.DeleteDevice(ctx, "RD-2"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteDevice twice: got %v, want store.ErrNotFound", err)
+	}
+
+	devices, err := s.ListDevices(ctx)
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	checkTags(t, "ListDevices after deleting", devices, []string{"RD-1", "RD-3"})
+}
+
+func testReservations(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	const ada, grace = "ada@example.com", "grace@example.com"
+
+	for _, tag := range []string{"RS-1", "RS-2", "RS-3"} {
+		if err := s.PutDevice(ctx, model.Device{AssetTag: tag, DeviceType: "Phone"}); err != nil {
+			t.Fatalf("PutDevice %s: %v", tag, err)
+		}
+	}
+	if err := s.CheckoutDevice(ctx, "RS-2", ada, *at(1, 9), *at(10, 17)); err != nil {
+		t.Fatalf("CheckoutDevice: %v", err)
+	}
+	if err := s.SetDeviceStatus(ctx, "RS-3", model.StatusRetired); err != nil {
+		t.Fatalf("SetDeviceStatus: %v", err)
+	}
+
+	first := store.NewReservation("RS-1", grace, *at(5, 0), *at(8, 0))
+	err := s.CreateReservation(ctx, first)
+	if errors.Is(err, store.ErrUnsupported) {
+		if _, err := s.ListReservations(ctx, store.ReservationFilter{}); !errors.Is(err, store.ErrUnsupported) {
+			t.Errorf("ListReservations: got %v, want ErrUnsupported like CreateReservation", err)
+		}
+		t.Skip("provider does not store reservations")
+	}
+	if err != nil {
+		t.Fatalf("CreateReservation: %v", err)
+	}
+
+	var conflict *store.ReservationConflictError
+	err = s.CreateReservation(ctx, store.NewReservation("RS-1", ada, *at(7, 0), *at(9, 0)))
+	if !errors.As(err, &conflict) || conflict.Reservation == nil || conflict.Reservation.ReservationID != first.ReservationID {
+		t.Errorf("CreateReservation overlapping another: got %v, want a *store.ReservationConflictError naming it", err)
+	}
+	err = s.CreateReservation(ctx, store.NewReservation("RS-2", grace, *at(6, 0), *at(7, 0)))
+	if !errors.As(err, &conflict) || !strings.EqualFold(conflict.AssignedTo, ada) {
+		t.Errorf("CreateReservation during a checkout: got %v, want a *store.ReservationConflictError naming %s", err, ada)
+	}
+	err = s.CreateReservation(ctx, store.NewReservation("RS-3", grace, *at(6, 0), *at(7, 0)))
+	checkTransitionError(t, "CreateReservation of a retired device", err, model.StatusRetired, model.StatusCheckedOut)
+	if err := s.CreateReservation(ctx, store.NewReservation("RS-9", grace, *at(6, 0), *at(7, 0))); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("CreateReservation of an unknown device: got %v, want store.ErrNotFound", err)
+	}
+	backwards := store.NewReservation("RS-1", grace, *at(20, 0), *at(19, 0))
+	if err := s.CreateReservation(ctx, backwards); err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("CreateReservation ending before it starts: got %v, want a validation error", err)
+	}
+
+	valid := []model.Reservation{
+		store.NewReservation("RS-1", ada, *at(8, 0), *at(10, 0)),    // Starts as the first ends
+		store.NewReservation("RS-2", grace, *at(11, 0), *at(12, 0)), // After the checkout is due
+		store.NewReservation("RS-2", ada, *at(6, 0), *at(7, 0)),     // By the current holder
+	}
+	for _, r := range valid {
+		if err := s.CreateReservation(ctx, r); err != nil {
+			t.Errorf("CreateReservation of %s from %s: %v", r.AssetTag, r.Start, err)
+		}
+	}
+
+	all, err := s.ListReservations(ctx, store.ReservationFilter{})
+	if err != nil {
+		t.Fatalf("ListReservations: %v", err)
+	}
+	want := []model.Reservation{first, valid[2], valid[0], valid[1]}
+	if len(all) != len(want) {
+		t.Fatalf("ListReservations returned %d reservations, want %d", len(all), len(want))
+	}
+	for i, got := range all {
+		w := want[i]
+		if got.ReservationID != w.ReservationID || got.AssetTag != w.AssetTag || got.ReservedBy != w.ReservedBy ||
+			!got.Start.Equal(w.Start) || !got.End.Equal(w.End) || !got.CreatedAt.Equal(w.CreatedAt) {
+			t.Errorf("ListReservations[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+
+	filters := []struct {
+		name   string
+		filter store.ReservationFilter
+		want   []string
+	}{
+		{"device", store.ReservationFilter{AssetTag: "RS-1"}, []string{first.ReservationID, valid[0].ReservationID}},
+		{"person", store.ReservationFilter{ReservedBy: "Grace@Example.com"}, []string{first.ReservationID, valid[1].ReservationID}},
+		{"window", store.ReservationFilter{From: at(7, 0), To: at(9, 0)}, []string{first.ReservationID, valid[0].ReservationID}},
+		{"from", store.ReservationFilter{From: at(10, 0)}, []string{valid[1].ReservationID}},
+	}
+	for _, tt := range filters {
+		got, err := s.ListReservations(ctx, tt.filter)
+		if err != nil {
+			t.Errorf("ListReservations (%s): %v", tt.name, err)
+			continue
+		}
+		ids := make([]string, len(got))
+		for i, r := range got {
+			ids[i] = r.ReservationID
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("ListReservations (%s) returned %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+
+	if err := s.CancelReservation(ctx, "RS-1", first.ReservationID); err != nil {
+		t.Fatalf("CancelReservation: %v", err)
+	}
+	if err := s.CancelReservation(ctx, "RS-1", first.ReservationID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("CancelReservation twice: got %v, want store.ErrNotFound", err)
+	}
+	if err := s.CreateReservation(ctx, store.NewReservation("RS-1", ada, *at(7, 0), *at(8, 0))); err != nil {
+		t.Errorf("CreateReservation in a cancelled window: %v", err)
+	}
+
+	// Checkouts are checked against reservations by others in the same step.
+	err = s.CheckoutDevice(ctx, "RS-1", grace, *at(1, 9), *at(9, 0))
+	if !errors.As(err, &conflict) || conflict.Reservation == nil || !strings.EqualFold(conflict.Reservation.ReservedBy, ada) {
+		t.Errorf("CheckoutDevice over a reservation: got %v, want a *store.ReservationConflictError naming %s's reservation", err, ada)
+	}
+	if err := s.CheckoutDevice(ctx, "RS-1", grace, *at(1, 9), *at(7, 0)); err != nil {
+		t.Errorf("CheckoutDevice due back as a reservation starts: %v", err)
+	}
+	err = s.CheckoutDevice(ctx, "RS-2", ada, *at(1, 9), *at(11, 12))
+	if !errors.As(err, &conflict) || conflict.Reservation == nil || !strings.EqualFold(conflict.Reservation.ReservedBy, grace) {
+		t.Errorf("CheckoutDevice renewing into a reservation: got %v, want a *store.ReservationConflictError naming %s's reservation", err, grace)
+	}
+	if err := s.CheckoutDevice(ctx, "RS-2", ada, *at(1, 9), *at(11, 0)); err != nil {
+		t.Errorf("CheckoutDevice renewing over the holder's own reservation: %v", err)
+	}
+
+	if err := s.DeleteDevice(ctx, "RS-2"); err != nil {
+		t.Fatalf("DeleteDevice: %v", err)
+	}
+	left, err := s.ListReservations(ctx, store.ReservationFilter{AssetTag: "RS-2"})
+	if err != nil {
+		t.Fatalf("ListReservations: %v", err)
+	}
+	if len(left) != 0 {
+		t.Errorf("ListReservations of a deleted device returned %d reservations, want none", len(left))
+	}
+}
+
+func testWaitlist(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	const ada, grace = "ada@example.com", "grace@example.com"
+
+	// Joined out of order, to check that ListWaitlist is first come, first served.
+	second := store.NewWaitlistEntry("iPhone", grace, "U2")
+	second.JoinedAt = *at(2, 9)
+	first := store.NewWaitlistEntry("iphone", ada, "U1")
+	first.JoinedAt = *at(1, 9)
+	third := store.NewWaitlistEntry("pixel", ada, "U1")
+	third.JoinedAt = *at(3, 9)
+
+	err := s.JoinWaitlist(ctx, second)
+	if errors.Is(err, store.ErrUnsupported) {
+		if _, err := s.ListWaitlist(ctx, store.WaitlistFilter{}); !errors.Is(err, store.ErrUnsupported) {
+			t.Errorf("ListWaitlist: got %v, want ErrUnsupported like JoinWaitlist", err)
+		}
+		t.Skip("provider does not store the waitlist")
+	}
+	if err != nil {
+		t.Fatalf("JoinWaitlist: %v", err)
+	}
+	for _, e := range []model.WaitlistEntry{first, third} {
+		if err := s.JoinWaitlist(ctx, e); err != nil {
+			t.Fatalf("JoinWaitlist %s: %v", e.Query, err)
+		}
+	}
+
+	var conflict *store.WaitlistConflictError
+	if err := s.JoinWaitlist(ctx, first); !errors.As(err, &conflict) || conflict.Offer != nil {
+		t.Errorf("JoinWaitlist twice: got %v, want a *store.WaitlistConflictError", err)
+	}
+	if err := s.JoinWaitlist(ctx, store.NewWaitlistEntry(" ", ada, "U1")); err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("JoinWaitlist without a query: got %v, want a validation error", err)
+	}
+
+	all, err := s.ListWaitlist(ctx, store.WaitlistFilter{})
+	if err != nil {
+		t.Fatalf("ListWaitlist: %v", err)
+	}
+	want := []model.WaitlistEntry{first, second, third}
+	if len(all) != len(want) {
+		t.Fatalf("ListWaitlist returned %d entries, want %d", len(all), len(want))
+	}
+	for i, got := range all {
+		w := want[i]
+		if got.EntryID != w.EntryID || got.Query != w.Query || got.Requester != w.Requester ||
+			got.SlackUserID != w.SlackUserID || !got.JoinedAt.Equal(w.JoinedAt) || got.Offer != nil {
+			t.Errorf("ListWaitlist[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+
+	offer := model.WaitlistOffer{AssetTag: "WL-1", ReservationID: "r1", OfferedAt: *at(4, 9), ExpiresAt: *at(5, 9)}
+	if err := s.OfferWaitlistEntry(ctx, first.EntryID, offer); err != nil {
+		t.Fatalf("OfferWaitlistEntry: %v", err)
+	}
+	again := model.WaitlistOffer{AssetTag: "WL-2", ReservationID: "r2", OfferedAt: *at(4, 9), ExpiresAt: *at(5, 9)}
+	err = s.OfferWaitlistEntry(ctx, first.EntryID, again)
+	if !errors.As(err, &conflict) || conflict.Offer == nil || conflict.Offer.AssetTag != "WL-1" {
+		t.Errorf("OfferWaitlistEntry twice: got %v, want a *store.WaitlistConflictError naming WL-1", err)
+	}
+	if err := s.OfferWaitlistEntry(ctx, "missing", again); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("OfferWaitlistEntry of an unknown entry: got %v, want store.ErrNotFound", err)
+	}
+	expired := model.WaitlistOffer{AssetTag: "WL-2", ReservationID: "r2", OfferedAt: *at(5, 9), ExpiresAt: *at(4, 9)}
+	if err := s.OfferWaitlistEntry(ctx, second.EntryID, expired); err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("OfferWaitlistEntry expiring before it is made: got %v, want a validation error", err)
+	}
+
+	held, err := s.ListWaitlist(ctx, store.WaitlistFilter{AssetTag: "WL-1"})
+	if err != nil {
+		t.Fatalf("ListWaitlist: %v", err)
+	}
+	if len(held) != 1 || held[0].EntryID != first.EntryID || held[0].Offer == nil ||
+		held[0].Offer.ReservationID != offer.ReservationID ||
+		!held[0].Offer.OfferedAt.Equal(offer.OfferedAt) || !held[0].Offer.ExpiresAt.Equal(offer.ExpiresAt) {
+		t.Errorf("ListWaitlist (device) = %+v, want %s holding WL-1", held, first.EntryID)
+	}
+
+	filters := []struct {
+		name   string
+		filter store.WaitlistFilter
+		want   []string
+	}{
+		{"query", store.WaitlistFilter{Query: "IPHONE"}, []string{first.EntryID, second.EntryID}},
+		{"person", store.WaitlistFilter{Requester: "Ada@Example.com"}, []string{first.EntryID, third.EntryID}},
+		{"unheld device", store.WaitlistFilter{AssetTag: "WL-2"}, nil},
+	}
+	for _, tt := range filters {
+		got, err := s.ListWaitlist(ctx, tt.filter)
+		if err != nil {
+			t.Errorf("ListWaitlist (%s): %v", tt.name, err)
+			continue
+		}
+		var ids []string
+		for _, e := range got {
+			ids = append(ids, e.EntryID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("ListWaitlist (%s) returned %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+
+	// Two devices coming back at once must not both be held for the same person.
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.OfferWaitlistEntry(ctx, third.EntryID, model.WaitlistOffer{
+				AssetTag: fmt.Sprintf("WL-%d", 10+i), ReservationID: fmt.Sprintf("r%d", 10+i),
+				OfferedAt: *at(4, 9), ExpiresAt: *at(5, 9),
+			})
+		}()
+	}
+	wg.Wait()
+	won := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, store.ErrConflict):
+			t.Errorf("OfferWaitlistEntry of WL-%d: got %v, want success or store.ErrConflict", 10+i, err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d concurrent offers of the same entry succeeded, want 1", won)
+	}
+
+	if err := s.LeaveWaitlist(ctx, first.EntryID); err != nil {
+		t.Fatalf("LeaveWaitlist: %v", err)
+	}
+	if err := s.LeaveWaitlist(ctx, first.EntryID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("LeaveWaitlist twice: got %v, want store.ErrNotFound", err)
+	}
+	left, err := s.ListWaitlist(ctx, store.WaitlistFilter{Query: "iphone"})
+	if err != nil {
+		t.Fatalf("ListWaitlist: %v", err)
+	}
+	if len(left) != 1 || left[0].EntryID != second.EntryID {
+		t.Errorf("ListWaitlist after leaving = %+v, want only %s", left, second.EntryID)
+	}
+}
+
+func testHistory(t *testing.T, s store.Store) {
+	ctx := store.WithActor(context.Background(), "ada@example.com")
+	now := time.Now()
+
+	if err := s.PutDevice(ctx, model.Device{AssetTag: "H-1", DeviceType: "Laptop"}); err != nil {
+		t.Fatalf("PutDevice: %v", err)
+	}
+	if err := s.CheckoutDevice(store.WithWriteID(ctx, "checkout-1"), "H-1", "ada@example.com", now, now.AddDate(0, 0, 30)); err != nil {
+		t.Fatalf("CheckoutDevice: %v", err)
+	}
+	// Someone else may return a device, and the history names them.
+	if err := s.ReturnDevice(store.WithActor(ctx, "grace@example.com"), "H-1", "ada@example.com"); err != nil {
+		t.Fatalf("ReturnDevice: %v", err)
+	}
+
+	events, err := s.ListDeviceEvents(ctx, "H-1")
+	if err != nil {
+		t.Fatalf("ListDeviceEvents: %v", err)
+	}
+
+	var types []model.DeviceEventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	want := []model.DeviceEventType{model.DeviceCreated, model.DeviceCheckedOut, model.DeviceReturned}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Fatalf("ListDeviceEvents returned events %v, want %v", types, want)
+	}
+
+	actors := []string{"ada@example.com", "ada@example.com", "grace@example.com"}
+	for i, e := range events {
+		if e.AssetTag != "H-1" || e.EventID == "" || e.Timestamp.IsZero() {
+			t.Errorf("event %d is incomplete: %+v", i, e)
+		}
+		if e.Actor != actors[i] {
+			t.Errorf("event %d was made by %q, want %q", i, e.Actor, actors[i])
+		}
+		if i > 0 && e.Timestamp.Before(events[i-1].Timestamp) {
+			t.Errorf("event %d is older than the event before it", i)
+		}
+	}
+	if events[0].Before != nil {
+		t.Errorf("the create event has a Before snapshot: %+v", events[0].Before)
+	}
+	if events[1].WriteID != "checkout-1" {
+		t.Errorf("the checkout event has write ID %q, want the one it was made with", events[1].WriteID)
+	}
+
+	current, err := s.GetDevice(ctx, "H-1")
+	if err != nil {
+		t.Fatalf("GetDevice: %v", err)
+	}
+	if last := events[len(events)-1]; last.After == nil {
+		t.Error("the last event has no After snapshot")
+	} else {
+		checkDevice(t, "the last event's After snapshot", *last.After, current)
+	}
+
+	if _, _, err := store.DeviceAsOf(ctx, s, "H-9", now); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeviceAsOf an unknown device: got %v, want store.ErrNotFound", err)
+	}
+
+	checkedOutAt := events[1].Timestamp
+	checkInventoryAsOf(t, s, checkedOutAt, "ada@example.com")
+
+	// A deleted device can still be rebuilt from the history it leaves behind.
+	err = s.DeleteDevice(ctx, "H-1")
+	if errors.Is(err, store.ErrUnsupported) {
+		return
+	}
+	if err != nil {
+		t.Fatalf("DeleteDevice: %v", err)
+	}
+	if kept, err := s.ListDeviceEvents(ctx, "H-1"); err != nil {
+		t.Fatalf("ListDeviceEvents: %v", err)
+	} else if len(kept) != len(events)+1 || kept[len(kept)-1].Type != model.DeviceDeleted {
+		t.Errorf("ListDeviceEvents after DeleteDevice returned %d events, want the %d before it and a delete event", len(kept), len(events))
+	}
+	past, existed, err := store.DeviceAsOf(ctx, s, "H-1", checkedOutAt)
+	if err != nil || !existed || !strings.EqualFold(past.AssignedTo, "ada@example.com") {
+		t.Errorf("DeviceAsOf a deleted device while checked out = %+v, %v, %v; want it checked out to ada@example.com", past, existed, err)
+	}
+	if _, existed, err := store.DeviceAsOf(ctx, s, "H-1", time.Now().Add(time.Minute)); err != nil || existed {
+		t.Errorf("DeviceAsOf a deleted device after the delete: existed %v, err %v; want false, nil", existed, err)
+	}
+	checkInventoryAsOf(t, s, checkedOutAt, "ada@example.com")
+	checkInventoryAsOf(t, s, time.Now().Add(time.Minute), "")
+}
+
+// checkInventoryAsOf checks that InventoryAsOf at the given time holds H-1 assigned to
+// assignee, or no device at all if assignee is empty.
+func checkInventoryAsOf(t *testing.T, s store.Store, at time.Time, assignee string) {
+	t.Helper()
+
+	devices, err := store.InventoryAsOf(context.Background(), s, at)
+	if err != nil {
+		t.Fatalf("InventoryAsOf: %v", err)
+	}
+	switch {
+	case assignee == "" && len(devices) != 0:
+		t.Errorf("InventoryAsOf after every device was deleted = %+v, want none", devices)
+	case assignee != "" && (len(devices) != 1 || devices[0].AssetTag != "H-1" || !strings.EqualFold(devices[0].AssignedTo, assignee)):
+		t.Errorf("InventoryAsOf while H-1 was checked out = %+v, want only H-1 checked out to %s", devices, assignee)
+	}
+}
+
+// putDevices stores n devices with distinct tags and returns them.
+func putDevices(t *testing.T, s store.Store, n int) []model.Device {
+	t.Helper()
+
+	devices := make([]model.Device, n)
+	for i := range devices {
+		devices[i] = model.Device{AssetTag: fmt.Sprintf("D-%03d", i), DeviceType: "Laptop", Location: "HQ"}
+		if err := s.PutDevice(context.Background(), devices[i]); err != nil {
+			t.Fatalf("PutDevice %s: %v", devices[i].AssetTag, err)
+		}
+	}
+	return devices
+}
+
+// checkDevice reports every field of got that differs from want. Timestamps are compared
+// as instants, so the zone they come back in does not matter.
+func checkDevice(t *testing.T, what string, got, want model.Device) {
+	t.Helper()
+	for _, c := range model.DiffDevices(want, got) {
+		t.Errorf("%s: %s of %s = %q, want %q", what, c.Field, want.AssetTag, c.After, c.Before)
+	}
+}
+
+// checkDeviceSet compares two sets of devices regardless of order.
+func checkDeviceSet(t *testing.T, what string, got, want []model.Device) {
+	t.Helper()
+
+	byTag := make(map[string]model.Device, len(got))
+	for _, d := range got {
+		if _, dup := byTag[d.AssetTag]; dup {
+			t.Errorf("%s returned %s more than once", what, d.AssetTag)
+		}
+		byTag[d.AssetTag] = d
+	}
+	if len(byTag) != len(want) {
+		t.Errorf("%s returned %d devices, want %d", what, len(byTag), len(want))
+	}
+	for _, w := range want {
+		d, ok := byTag[w.AssetTag]
+		if !ok {
+			t.Errorf("%s did not return %s", what, w.AssetTag)
+			continue
+		}
+		checkDevice(t, what, d, w)
+	}
+}
+
+// checkTags compares the asset tags of devices with want, regardless of order.
+func checkTags(t *testing.T, what string, devices []model.Device, want []string) {
+	t.Helper()
+
+	got := make(map[string]int, len(devices))
+	for _, d := range devices {
+		got[d.AssetTag]++
+	}
+	wanted := make(map[string]int, len(want))
+	for _, tag := range want {
+		wanted[tag]++
+	}
+	if fmt.Sprint(got) != fmt.Sprint(wanted) {
+		t.Errorf("%s returned %v, want %v", what, tagsOf(devices), want)
+	}
+}
+
+// checkTagOrder compares the asset tags of devices with want, in order.
+func checkTagOrder(t *testing.T, what string, devices []model.Device, want []string) {
+	t.Helper()
+	if got := tagsOf(devices); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s returned %v, want %v", what, got, want)
+	}
+}
+
+func tagsOf(devices []model.Device) []string {
+	tags := make([]string, len(devices))
+	for i, d := range devices {
+		tags[i] = d.AssetTag
+	}
+	return tags
+}
+
+// checkTransitionError fails the test unless err is a *store.TransitionError from one
+// status to another.
+func checkTransitionError(t *testing.T, what string, err error, from, to model.DeviceStatus) {
+	t.Helper()
+	var transition *store.TransitionError
+	if !errors.As(err, &transition) || transition.From != from || transition.To != to {
+		t.Errorf("%s: got %v, want a *store.TransitionError from %s to %s", what, err, from, to)
+	}
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// ValidatingStore wraps a Store and checks the custom attributes of every device written
+// through it against the schema of its type, storing them in canonical form. Writes that
+// do not match are rejected with a *ValidationError before they reach the wrapped store.
+type ValidatingStore struct {
+	next    Store
+	schemas *model.SchemaRegistry
+}
+
+var _ Store = (*ValidatingStore)(nil)
+
+// NewValidatingStore returns a Store that validates writes to next against schemas.
+func NewValidatingStore(next Store, schemas *model.SchemaRegistry) *ValidatingStore {
+	return &ValidatingStore{next: next, schemas: schemas}
+}
+
+// Close closes the wrapped store.
+func (v *ValidatingStore) Close() error {
+	return v.next.Close()
+}
+
+func (v *ValidatingStore) PutDevice(ctx context.Context, device model.Device) error {
+	device, err := v.validate(device)
+	if err != nil {
+		return err
+	}
+	return v.next.PutDevice(ctx, device)
+}
+
+// BatchPutDevices writes nothing unless every device is valid.
+func (v *ValidatingStore) BatchPutDevices(ctx context.Context, devices []model.Device) error {
+	valid := make([]model.Device, len(devices))
+	for i, device := range devices {
+		device, err := v.validate(device)
+		if err != nil {
+			return err
+		}
+		valid[i] = device
+	}
+	return v.next.BatchPutDevices(ctx, valid)
+}
+
+// UpdateDevice validates the device as the patch would leave it. Patches that change
+// neither the device type nor custom attributes are passed through unread.
+func (v *ValidatingStore) UpdateDevice(ctx context.Context, deviceID string, patch DevicePatch) error {
+	if len(patch.Attributes) == 0 && !patch.DeviceType.Changed() {
+		return v.next.UpdateDevice(ctx, deviceID, patch)
+	}
+
+	current, err := v.next.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+
+	attrs := make(map[string]Field[string], len(patch.Attributes))
+	for key, f := range patch.Attributes {
+		attrs[strings.ToLower(strings.TrimSpace(key))] = f
+	}
+	patch.Attributes = attrs
+
+	after, err := v.validate(patch.Apply(current))
+	if err != nil {
+		return err
+	}
+	for key, f := range patch.Attributes {
+		if _, ok := f.Get(); ok {
+			patch.Attributes[key] = Set(after.Attributes[key])
+		}
+	}
+	return v.next.UpdateDevice(ctx, deviceID, patch)
+}
+
+// validate returns the device with its custom attributes in canonical form, or a
+// *ValidationError listing everything wrong with them.
+func (v *ValidatingStore) validate(device model.Device) (model.Device, error) {
+	normalized, problems := v.schemas.NormalizeDevice(device)
+	if len(problems) > 0 {
+		return model.Device{}, &ValidationError{AssetTag: device.AssetTag, Problems: problems}
+	}
+	return normalized, nil
+}
+
+func (v *ValidatingStore) GetDevice(ctx context.Context, deviceID string) (model.Device, error) {
+	return v.next.GetDevice(ctx, deviceID)
+}
+
+func (v *ValidatingStore) ListDevices(ctx context.Context) ([]model.Device, error) {
+	return v.next.ListDevices(ctx)
+}
+
+func (v *ValidatingStore) IterateDevices(ctx context.Context, fn func(model.Device) error) error {
+	return v.next.IterateDevices(ctx, fn)
+}
+
+func (v *ValidatingStore) QueryDevices(ctx context.Context, filter DeviceFilter) ([]model.Device, error) {
+	return v.next.QueryDevices(ctx, filter)
+}
+
+func (v *ValidatingStore) BatchGetDevices(ctx context.Context, deviceIDs []string) ([]model.Device, error) {
+	return v.next.BatchGetDevices(ctx, deviceIDs)
+}
+
+func (v *ValidatingStore) CheckoutDevice(ctx context.Context, deviceID, assignee string, assignedAt, dueAt time.Time) error {
+	return v.next.CheckoutDevice(ctx, deviceID, assignee, assignedAt, dueAt)
+}
+
+func (v *ValidatingStore) ReturnDevice(ctx context.Context, deviceID, assignee string) error {
+	return v.next.ReturnDevice(ctx, deviceID, assignee)
+}
+
+func (v *ValidatingStore) DeleteDevice(ctx context.Context, deviceID string) error {
+	return v.next.DeleteDevice(ctx, deviceID)
+}
+
+func (v *ValidatingStore) SetDeviceStatus(ctx context.Context, deviceID string, status model.DeviceStatus) error {
+	return v.next.SetDeviceStatus(ctx, deviceID, status)
+}
+
+func (v *ValidatingStore) CreateReservation(ctx context.Context, r model.Reservation) error {
+	return v.next.CreateReservation(ctx, r)
+}
+
+func (v *ValidatingStore) ListReservations(ctx context.Context, filter ReservationFilter) ([]model.Reservation, error) {
+	return v.next.ListReservations(ctx, filter)
+}
+
+func (v *ValidatingStore) CancelReservation(ctx context.Context, deviceID, reservationID string) error {
+	return v.next.CancelReservation(ctx, deviceID, reservationID)
+}
+
+func (v *ValidatingStore) JoinWaitlist(ctx context.Context, e model.WaitlistEntry) error {
+	return v.next.JoinWaitlist(ctx, e)
+}
+
+func (v *ValidatingStore) ListWaitlist(ctx context.Context, filter WaitlistFilter) ([]model.WaitlistEntry, error) {
+	return v.next.ListWaitlist(ctx, filter)
+}
+
+func (v *ValidatingStore) OfferWaitlistEntry(ctx context.Context, entryID string, offer model.WaitlistOffer) error {
+	return v.next.OfferWaitlistEntry(ctx, entryID, offer)
+}
+
+func (v *ValidatingStore) LeaveWaitlist(ctx context.Context, entryID string) error {
+	return v.next.LeaveWaitlist(ctx, entryID)
+}
+
+func (v *ValidatingStore) ListDeviceEvents(ctx context.Context, deviceID string) ([]model.DeviceEvent, error) {
+	return v.next.ListDeviceEvents(ctx, deviceID)
+}
+
+func (v *ValidatingStore) IterateDeviceEvents(ctx context.Context, fn func(model.DeviceEvent) error) error {
+	return v.next.IterateDeviceEvents(ctx, fn)
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"bdemetris/curator/pkg/model"
+)
+
+// NewWaitlistEntry returns an entry for requester at the back of the waitlist for query,
+// with a new ID. Like NewReservation, the caller sets the ID so a retried join cannot
+// queue the person twice.
+func NewWaitlistEntry(query, requester, slackUserID string) model.WaitlistEntry {
+	id := make([]byte, 4)
+	rand.Read(id)
+
+	return model.WaitlistEntry{
+		EntryID:     hex.EncodeToString(id),
+		Query:       strings.ToLower(strings.TrimSpace(query)),
+		Requester:   requester,
+		SlackUserID: slackUserID,
+		JoinedAt:    time.Now().UTC(),
+	}
+}
+
+// ValidateWaitlistEntry checks that a new waitlist entry is complete and not yet offered
+// a device.
+func ValidateWaitlistEntry(e model.WaitlistEntry) error {
+	switch {
+	case e.EntryID == "":
+		return errors.New("waitlist entry is missing EntryID")
+	case strings.TrimSpace(e.Query) == "":
+		return errors.New("waitlist entry is missing Query")
+	case strings.TrimSpace(e.Requester) == "":
+		return errors.New("waitlist entry is missing Requester")
+	case e.JoinedAt.IsZero():
+		return errors.New("waitlist entry is missing JoinedAt")
+	case e.Offer != nil:
+		return errors.New("waitlist entries are offered devices with OfferWaitlistEntry")
+	}
+	return nil
+}
+
+// ValidateWaitlistOffer checks that an offer names a device and a hold that ends after
+// it starts.
+func ValidateWaitlistOffer(o model.WaitlistOffer) error {
+	switch {
+	case o.AssetTag == "":
+		return errors.New("waitlist offer is missing AssetTag")
+	case o.ReservationID == "":
+		return errors.New("waitlist offer is missing ReservationID")
+	case !o.OfferedAt.Before(o.ExpiresAt):
+		return errors.New("waitlist offer must expire after it is made")
+	}
+	return nil
+}
+
+// SortWaitlist orders entries first come, first served.
+func SortWaitlist(entries []model.WaitlistEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.JoinedAt.Equal(b.JoinedAt) {
+			return a.JoinedAt.Before(b.JoinedAt)
+		}
+		return a.EntryID < b.EntryID
+	})
+}